@@ -0,0 +1,294 @@
+package yago
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	AccessLogFormatCommon   string = "common"
+	AccessLogFormatCombined string = "combined"
+	AccessLogFormatJSON     string = "json"
+
+	defaultAccessLogMaxSize    = 100 << 20
+	defaultAccessLogMaxBackups = 7
+
+	clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+)
+
+// YagoAccessLogConfig
+// YagoAccessLogConfig writes a record for every request served by Yago
+type YagoAccessLogConfig struct {
+	// Format is common, combined or json, default common. Common and combined records
+	// are the Common and Combined Log Format followed by the latency in seconds, eg: rt=0.012,
+	// and the YagoAPIWrapper.Code of api requests, eg: code=-100001
+	Format string `json:"format"`
+
+	// File is the path of the access log, rotated once it reaches MaxSize, empty writes to Out
+	File string `json:"file"`
+
+	// MaxSize is the size in bytes File is rotated at, default 100MB
+	MaxSize int64 `json:"maxSize"`
+
+	// MaxBackups is the count of rotated files kept as File.1, File.2 ..., default 7
+	MaxBackups int `json:"maxBackups"`
+
+	// Out receives records when File is empty, default stdout
+	Out io.Writer `json:"-"`
+}
+
+type YagoAccessLog struct {
+	c   *YagoAccessLogConfig
+	out io.Writer
+	mu  sync.Mutex
+}
+
+// yagoAccessRecord is a record of the json format
+type yagoAccessRecord struct {
+	Time       string  `json:"time"`
+	RequestID  string  `json:"requestId"`
+	RemoteAddr string  `json:"remoteAddr"`
+	User       string  `json:"user,omitempty"`
+	Method     string  `json:"method"`
+	URI        string  `json:"uri"`
+	Proto      string  `json:"proto"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	Latency    float64 `json:"latency"`
+	Referer    string  `json:"referer,omitempty"`
+	UserAgent  string  `json:"userAgent,omitempty"`
+	Code       *int    `json:"code,omitempty"`
+}
+
+func NewYagoAccessLog(c *YagoAccessLogConfig) (*YagoAccessLog, error) {
+
+	if c == nil {
+		return nil, errors.New("empty access log config is not allowed")
+	}
+	switch c.Format {
+	case "":
+		c.Format = AccessLogFormatCommon
+	case AccessLogFormatCommon, AccessLogFormatCombined, AccessLogFormatJSON:
+	default:
+		return nil, errors.New("unknown access log format: " + c.Format)
+	}
+
+	y := &YagoAccessLog{c: c, out: c.Out}
+	if c.File != "" {
+		w, err := newYagoRotateWriter(c.File, c.MaxSize, c.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		y.out = w
+	}
+	if y.out == nil {
+		y.out = os.Stdout
+	}
+	return y, nil
+}
+
+// Close closes the access log file
+func (y *YagoAccessLog) Close() error {
+	if c, ok := y.out.(io.Closer); ok && y.c.File != "" {
+		return c.Close()
+	}
+	return nil
+}
+
+func (y *YagoAccessLog) log(r *http.Request, w *yagoResponseWriter, info *yagoRequestInfo, start time.Time) {
+
+	latency := time.Since(start)
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		host = h
+	}
+	user, _, _ := r.BasicAuth()
+
+	var line []byte
+	if y.c.Format == AccessLogFormatJSON {
+		record := &yagoAccessRecord{
+			Time:       start.Format(time.RFC3339Nano),
+			RequestID:  info.id,
+			RemoteAddr: host,
+			User:       user,
+			Method:     r.Method,
+			URI:        r.RequestURI,
+			Proto:      r.Proto,
+			Status:     w.statusCode(),
+			Bytes:      w.size,
+			Latency:    latency.Seconds(),
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+		}
+		if info.hasAPICode {
+			code := info.apiCode
+			record.Code = &code
+		}
+		line, _ = json.Marshal(record)
+	} else {
+		if user == "" {
+			user = "-"
+		}
+		size := "-"
+		if w.size > 0 {
+			size = strconv.FormatInt(w.size, 10)
+		}
+		// the request line is quoted like referer and user agent, so a quote in the uri
+		// cannot forge fields of the line
+		line = []byte(fmt.Sprintf(`%s - %s [%s] %q %d %s`,
+			host, user, start.Format(clfTimeFormat), r.Method+" "+r.RequestURI+" "+r.Proto, w.statusCode(), size))
+		if y.c.Format == AccessLogFormatCombined {
+			line = append(line, fmt.Sprintf(` %q %q`, r.Referer(), r.UserAgent())...)
+		}
+		line = append(line, fmt.Sprintf(" rt=%.3f", latency.Seconds())...)
+		if info.hasAPICode {
+			line = append(line, " code="+strconv.Itoa(info.apiCode)...)
+		}
+	}
+	line = append(line, '\n')
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.out.Write(line)
+}
+
+// yagoResponseWriter records the status and the body size of a response
+type yagoResponseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *yagoResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *yagoResponseWriter) Write(bs []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(bs)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *yagoResponseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Flush keeps streamed template pages streaming
+func (w *yagoResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack keeps websocket upgrades working
+func (w *yagoResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijack")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Unwrap supports http.ResponseController
+func (w *yagoResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// yagoRotateWriter appends to a file and rotates it to file.1, file.2 ... once it reaches maxSize
+type yagoRotateWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	f    *os.File
+	size int64
+	mu   sync.Mutex
+}
+
+func newYagoRotateWriter(path string, maxSize int64, maxBackups int) (*yagoRotateWriter, error) {
+	if maxSize <= 0 {
+		maxSize = defaultAccessLogMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = defaultAccessLogMaxBackups
+	}
+	w := &yagoRotateWriter{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *yagoRotateWriter) Write(bs []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size > 0 && w.size+int64(len(bs)) > w.maxSize {
+		// a failed rotation keeps appending to the reopened file and is retried on the next write
+		w.rotate()
+	}
+	n, err := w.f.Write(bs)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *yagoRotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.f.Close()
+}
+
+func (w *yagoRotateWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f, w.size = f, info.Size()
+	return nil
+}
+
+// rotate reopens path whatever fails, so logging never stops until a restart
+func (w *yagoRotateWriter) rotate() error {
+	closeErr := w.f.Close()
+	os.Remove(w.backup(w.maxBackups))
+	for i := w.maxBackups - 1; i > 0; i-- {
+		os.Rename(w.backup(i), w.backup(i+1))
+	}
+	renameErr := os.Rename(w.path, w.backup(1))
+	if err := w.open(); err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return renameErr
+}
+
+func (w *yagoRotateWriter) backup(i int) string {
+	return w.path + "." + strconv.Itoa(i)
+}
@@ -0,0 +1,106 @@
+package yago
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoAccessLog(t *testing.T) {
+
+	aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/a/", Timeout: 1000})
+	assert.Nil(t, aServer.Register("apidemo", func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) { return &DemoRsp{}, nil }))
+
+	var uts = []struct {
+		Format string
+		Path   string
+		Expect string
+	}{
+		{Format: "common", Path: "/a/apidemo", Expect: `^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "POST /a/apidemo\?x=1 HTTP/1\.1" 200 \d+ rt=\d+\.\d{3} code=0$`},
+		{Format: "common", Path: "/a/missing", Expect: ` "POST /a/missing\?x=1 HTTP/1\.1" 200 \d+ rt=\d+\.\d{3} code=-100001$`},
+		{Format: "common", Path: "/none", Expect: ` "POST /none\?x=1 HTTP/1\.1" 404 - rt=\d+\.\d{3}$`},
+		{Format: "common", Path: `/none"x`, Expect: ` "POST /none\\"x\?x=1 HTTP/1\.1" 404 - rt=\d+\.\d{3}$`},
+		{Format: "combined", Path: "/a/apidemo", Expect: ` 200 \d+ "http://ref" "yago-test" rt=\d+\.\d{3} code=0$`},
+	}
+
+	for _, ut := range uts {
+		buf := &bytes.Buffer{}
+		y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithAccessLog(&YagoAccessLogConfig{Format: ut.Format, Out: buf}), WithApiServer(aServer))
+		assert.Nil(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, ut.Path+"?x=1", strings.NewReader(`{}`))
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("Referer", "http://ref")
+		r.Header.Set("User-Agent", "yago-test")
+		y.ServeHTTP(httptest.NewRecorder(), r)
+
+		assert.Regexp(t, ut.Expect, strings.TrimSuffix(buf.String(), "\n"), ut.Format+ut.Path)
+	}
+
+	// json records go to a rotating file
+	file := filepath.Join(t.TempDir(), "access.log")
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithAccessLog(&YagoAccessLogConfig{Format: "json", File: file, MaxSize: 300, MaxBackups: 2}), WithApiServer(aServer))
+	assert.Nil(t, err)
+	for i := 0; i < 5; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/a/apidemo", strings.NewReader(`{}`))
+		r.SetBasicAuth("yago", "pwd")
+		y.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	assert.Nil(t, y.accessLog.Close())
+
+	bs, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	var record yagoAccessRecord
+	assert.Nil(t, json.Unmarshal(bytes.SplitN(bs, []byte("\n"), 2)[0], &record))
+	assert.Equal(t, "yago", record.User)
+	assert.Equal(t, "/a/apidemo", record.URI)
+	assert.Equal(t, http.StatusOK, record.Status)
+	assert.Equal(t, 0, *record.Code)
+	assert.Equal(t, 16, len(record.RequestID))
+
+	for _, name := range []string{file + ".1", file + ".2"} {
+		_, err := os.Stat(name)
+		assert.Nil(t, err, name)
+	}
+	_, err = os.Stat(file + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestYagoRotateWriterRenameFail(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "access.log")
+	w, err := newYagoRotateWriter(file, 10, 1)
+	assert.Nil(t, err)
+	defer w.Close()
+
+	// a non empty directory on the backup path fails the rename of the rotation
+	assert.Nil(t, os.MkdirAll(filepath.Join(file+".1", "dir"), 0755))
+
+	for _, line := range []string{"line 1 ...\n", "line 2 ...\n"} {
+		n, err := w.Write([]byte(line))
+		assert.Nil(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	bs, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "line 1 ...\nline 2 ...\n", string(bs))
+
+	// the next write rotates once the rename works again
+	assert.Nil(t, os.RemoveAll(file+".1"))
+	_, err = w.Write([]byte("line 3 ...\n"))
+	assert.Nil(t, err)
+
+	bs, err = os.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "line 3 ...\n", string(bs))
+	bs, err = os.ReadFile(file + ".1")
+	assert.Nil(t, err)
+	assert.Equal(t, "line 1 ...\nline 2 ...\n", string(bs))
+}
@@ -0,0 +1,154 @@
+package yago
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// YagoCheckError reports every problem found on startup together, see Yago.check
+type YagoCheckError struct {
+	Errs []error
+}
+
+func (e *YagoCheckError) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("yago check fail with %d errors: %s", len(e.Errs), strings.Join(msgs, "; "))
+}
+
+// Is supports errors.Is on each error, go 1.19 does not unwrap multiple errors
+func (e *YagoCheckError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As supports errors.As on each error
+func (e *YagoCheckError) As(target interface{}) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// yagoValidator is implemented by handlers checking their config on startup
+type yagoValidator interface {
+	validate() []error
+}
+
+// check validates the server config and the handlers, errs are option errors of New
+func (y *Yago) check(errs ...error) error {
+
+	if y.cfg == nil {
+		errs = append(errs, fmt.Errorf("empty server config is not allowed"))
+	} else if y.cfg.Port == 0 || y.cfg.Port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid server port %d, port must be in 1-65535", y.cfg.Port))
+	}
+
+	patterns := make(map[string]YagoHandler, len(y.handlers))
+	for _, h := range y.handlers {
+		if h.Pattern() == "" {
+			errs = append(errs, fmt.Errorf("empty route of %s is not allowed", h.Type()))
+			continue
+		}
+		if other, ok := patterns[h.Pattern()]; ok {
+			errs = append(errs, fmt.Errorf("route conflict: %s is served by both %s and %s", h.Pattern(), other.Type(), h.Type()))
+			continue
+		}
+		patterns[h.Pattern()] = h
+	}
+
+	errs = append(errs, y.checkRoutes()...)
+
+	for _, h := range y.handlers {
+		if v, ok := h.(yagoValidator); ok {
+			errs = append(errs, v.validate()...)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &YagoCheckError{Errs: errs}
+}
+
+// checkRoutes reports routes never reached since a handler with a longer pattern claims
+// their paths, eg: page /static/about below a file server on /static/
+func (y *Yago) checkRoutes() []error {
+
+	var errs []error
+	for _, h := range y.handlers {
+		namer, ok := h.(YagoRouteNamer)
+		if !ok {
+			continue
+		}
+		routes := namer.RouteNames()
+		keys := make([]string, 0, len(routes))
+		for name := range routes {
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+		for _, name := range keys {
+			pattern := routes[name]
+			prefix := pattern
+			if i := strings.Index(prefix, "{"); i >= 0 {
+				prefix = prefix[:i]
+			}
+			for _, other := range y.handlers {
+				if len(other.Pattern()) > len(h.Pattern()) && strings.HasPrefix(prefix, other.Pattern()) {
+					errs = append(errs, fmt.Errorf("route %s of %s is shadowed by %s on %s", pattern, h.Type(), other.Type(), other.Pattern()))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validate reports template files of the config matching no file
+func (y *YagoTemplateServer) validate() []error {
+
+	tmpls := append([]string{}, y.config().BaseLayouts...)
+	for _, page := range y.config().PageLayouts {
+		tmpls = append(tmpls, page.Templates...)
+	}
+	for _, layout := range y.config().Layouts {
+		tmpls = append(tmpls, layout.Templates...)
+	}
+	for _, layout := range y.config().ErrorLayouts {
+		tmpls = append(tmpls, layout.Templates...)
+	}
+	sort.Strings(tmpls)
+
+	var errs []error
+	for i, tmpl := range tmpls {
+		if i > 0 && tmpls[i-1] == tmpl {
+			continue
+		}
+		if matches, err := fs.Glob(y.layouts(), tmpl); err != nil || len(matches) == 0 {
+			errs = append(errs, fmt.Errorf("template file %s of %s not found", tmpl, y.Pattern()))
+		}
+	}
+	return errs
+}
+
+// validate reports a file server dir which does not exist
+func (y *YagoFileServer) validate() []error {
+	info, err := fs.Stat(y.fsys, ".")
+	if err != nil {
+		return []error{fmt.Errorf("dir %s of %s not found", y.fsConfig.Dir, y.fsPath)}
+	}
+	if !info.IsDir() {
+		return []error{fmt.Errorf("dir %s of %s is not a directory", y.fsConfig.Dir, y.fsPath)}
+	}
+	return nil
+}
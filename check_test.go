@@ -0,0 +1,152 @@
+package yago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoCheck(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"page.layout":       {Data: []byte(`page`)},
+		"static/app.js":     {Data: []byte(`app`)},
+		"static/about.html": {Data: []byte(`about`)},
+	}
+
+	newPages := func(route string, pages ...*PageLayoutConfig) *YagoTemplateServer {
+		tServer := newTestTemplateServer(t, &YagoTemplateConfig{Route: route, PageLayouts: pages}, fsys)
+		for _, page := range pages {
+			if err := tServer.Register(page.ServiceName, func(ctx *YagoContext) (interface{}, error) { return nil, nil }); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return tServer
+	}
+	newFiles := func(dir string) *YagoFileServer {
+		fServer, err := NewYagoFileServer(&YagoFileServerConfig{FS: fsys, Dir: dir, Route: "static"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fServer
+	}
+
+	newAbout := func(route string) *YagoApiServer {
+		aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: route})
+		if err := aServer.Register("about", func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) { return nil, nil }); err != nil {
+			t.Fatal(err)
+		}
+		return aServer
+	}
+
+	var uts = []struct {
+		Name   string
+		Opts   []Option
+		Expect []string
+	}{
+		{
+			Name: "valid",
+			Opts: []Option{WithConfig(&YagoConfig{Port: 8080}), WithTemplateServer(newPages("/", &PageLayoutConfig{ServiceName: "index", Path: "/", Templates: []string{"page.layout"}})), WithFileServer(newFiles("static"))},
+		},
+		{
+			Name:   "config",
+			Opts:   []Option{WithFileServer(nil)},
+			Expect: []string{"nil file server is not allowed", "empty server config is not allowed"},
+		},
+		{
+			Name:   "port",
+			Opts:   []Option{WithConfig(&YagoConfig{Port: 70000})},
+			Expect: []string{"invalid server port 70000, port must be in 1-65535"},
+		},
+		{
+			Name:   "conflict",
+			Opts:   []Option{WithConfig(&YagoConfig{Port: 8080}), WithFileServer(newFiles("static")), WithFileServer(newFiles("static"))},
+			Expect: []string{"route conflict: /static/ is served by both YagoFileServer and YagoFileServer"},
+		},
+		{
+			Name:   "shadow",
+			Opts:   []Option{WithConfig(&YagoConfig{Port: 8080}), WithTemplateServer(newPages("/", &PageLayoutConfig{ServiceName: "about", Path: "/static/about", Templates: []string{"page.layout"}})), WithFileServer(newFiles("static"))},
+			Expect: []string{"route /static/about of YagoTemplateServer is shadowed by YagoFileServer on /static/"},
+		},
+		{
+			// the same service name on several handlers is not a route conflict
+			Name: "names",
+			Opts: []Option{WithConfig(&YagoConfig{Port: 8080}), WithTemplateServer(newPages("/p", &PageLayoutConfig{ServiceName: "about", Templates: []string{"page.layout"}})), WithApiServer(newAbout("/api/v1/")), WithApiServer(newAbout("/api/v2/"))},
+		},
+		{
+			Name: "missing",
+			Opts: []Option{WithConfig(&YagoConfig{Port: 8080}), WithTemplateServer(newTestTemplateServer(t, &YagoTemplateConfig{
+				Route:        "/p",
+				BaseLayouts:  []string{"base.layout"},
+				PageLayouts:  []*PageLayoutConfig{{ServiceName: "page", Templates: []string{"page.layout", "*.tmpl"}}},
+				ErrorLayouts: []*ErrorLayoutConfig{{Templates: []string{"base.layout"}}},
+			}, fsys)), WithFileServer(newFiles("assets"))},
+			Expect: []string{
+				"template file *.tmpl of /p not found",
+				"template file base.layout of /p not found",
+				"dir assets of /static/ not found",
+			},
+		},
+	}
+
+	for _, ut := range uts {
+		y, err := New(ut.Opts...)
+		if len(ut.Expect) == 0 {
+			assert.Nil(t, err, ut.Name)
+			assert.NotNil(t, y, ut.Name)
+			continue
+		}
+
+		var checkErr *YagoCheckError
+		assert.True(t, errors.As(err, &checkErr), ut.Name)
+		msgs := []string{}
+		for _, e := range checkErr.Errs {
+			msgs = append(msgs, e.Error())
+		}
+		assert.Equal(t, ut.Expect, msgs, ut.Name)
+	}
+}
+
+func TestYagoCheckErrorUnwrap(t *testing.T) {
+
+	pathErr := &fs.PathError{Op: "open", Path: "assets", Err: fs.ErrNotExist}
+	err := error(&YagoCheckError{Errs: []error{errors.New("empty route"), fmt.Errorf("dir: %w", pathErr)}})
+
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.False(t, errors.Is(err, fs.ErrPermission))
+
+	var target *fs.PathError
+	assert.True(t, errors.As(err, &target))
+	assert.Equal(t, pathErr, target)
+}
+
+func TestYagoCheckOnStart(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"page.layout":       {Data: []byte(`page`)},
+		"static/about.html": {Data: []byte(`about`)},
+	}
+
+	// servers built from config files register their pages after New
+	y, err := NewFromConfig(&YagoFileConfig{
+		Server: &YagoConfig{Port: 8080},
+		Templates: []*YagoTemplateConfig{{
+			Route:       "/",
+			FS:          fsys,
+			PageLayouts: []*PageLayoutConfig{{ServiceName: "about", Path: "/static/about", Templates: []string{"page.layout"}}},
+		}},
+		Files: []*YagoFileServerConfig{{FS: fsys, Dir: "static", Route: "static"}},
+	}, WithLogger(nopLogger{}))
+	assert.Nil(t, err)
+	assert.Nil(t, y.TemplateServer("/").Register("about", func(ctx *YagoContext) (interface{}, error) { return nil, nil }))
+
+	err = y.Start(context.Background())
+	var checkErr *YagoCheckError
+	assert.True(t, errors.As(err, &checkErr))
+	assert.Equal(t, "route /static/about of YagoTemplateServer is shadowed by YagoFileServer on /static/", checkErr.Errs[0].Error())
+}
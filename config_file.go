@@ -0,0 +1,246 @@
+package yago
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix prefixes env vars overriding YagoConfig fields by their json names, eg: YAGO_PORT
+const envPrefix = "YAGO_"
+
+// YagoFileConfig
+// YagoFileConfig describes a whole Yago server in a JSON or YAML file, see LoadConfigFile
+type YagoFileConfig struct {
+	Server    *YagoConfig             `json:"server"`
+	Apis      []*YagoApiServerConfig  `json:"apis"`
+	Templates []*YagoTemplateConfig   `json:"templates"`
+	Files     []*YagoFileServerConfig `json:"files"`
+	Ws        []*YagoWsServerConfig   `json:"ws"`
+	I18n      *YagoI18nConfig         `json:"i18n"`
+	AccessLog *YagoAccessLogConfig    `json:"accessLog"`
+	Metrics   *YagoMetricsConfig      `json:"metrics"`
+	Tracing   *YagoTraceConfig        `json:"tracing"`
+	Health    *YagoHealthConfig       `json:"health"`
+}
+
+// LoadConfigFile reads a JSON or YAML (.yaml, .yml) config file, fields tagged with
+// default are set when empty and env vars override the server config afterwards
+func LoadConfigFile(file string) (*YagoFileConfig, error) {
+
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		// yaml is converted to json so that the json tags are the only field names
+		var v interface{}
+		if err := yaml.Unmarshal(bs, &v); err != nil {
+			return nil, fmt.Errorf("parse config file %s fail: %s", file, err.Error())
+		}
+		if bs, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("parse config file %s fail: %s", file, err.Error())
+		}
+	}
+
+	fc := &YagoFileConfig{}
+	if err := json.Unmarshal(bs, fc); err != nil {
+		return nil, fmt.Errorf("parse config file %s fail: %s", file, err.Error())
+	}
+	if fc.Server == nil {
+		fc.Server = &YagoConfig{}
+	}
+	if err := applyDefaults(reflect.ValueOf(fc)); err != nil {
+		return nil, err
+	}
+	if err := applyEnv(fc.Server); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// NewFromConfigFile builds a Yago with every server of the config file, handlers are
+// registered afterwards on the servers returned by Yago.ApiServer and Yago.TemplateServer
+func NewFromConfigFile(file string, opts ...Option) (*Yago, error) {
+	fc, err := LoadConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+	y, err := NewFromConfig(fc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	y.configFile = file
+	return y, nil
+}
+
+// NewFromConfig builds a Yago with every server of fc, opts are applied after them
+func NewFromConfig(fc *YagoFileConfig, opts ...Option) (*Yago, error) {
+
+	if fc == nil || fc.Server == nil {
+		return nil, errors.New("empty file config is not allowed")
+	}
+
+	fc.inheritTimeouts()
+	serverOpts := []Option{WithConfig(fc.Server)}
+
+	if fc.I18n != nil {
+		i18n, err := NewYagoI18n(fc.I18n)
+		if err != nil {
+			return nil, err
+		}
+		serverOpts = append(serverOpts, WithI18n(i18n))
+	}
+	if fc.AccessLog != nil {
+		serverOpts = append(serverOpts, WithAccessLog(fc.AccessLog))
+	}
+	if fc.Metrics != nil {
+		serverOpts = append(serverOpts, WithMetrics(fc.Metrics))
+	}
+	if fc.Tracing != nil {
+		serverOpts = append(serverOpts, WithTracing(fc.Tracing))
+	}
+	if fc.Health != nil {
+		health, err := NewYagoHealth(fc.Health)
+		if err != nil {
+			return nil, err
+		}
+		serverOpts = append(serverOpts, WithHealth(health))
+	}
+	for _, c := range fc.Apis {
+		aServer, err := NewYagoApiServer(c)
+		if err != nil {
+			return nil, err
+		}
+		serverOpts = append(serverOpts, WithApiServer(aServer))
+	}
+	for _, c := range fc.Templates {
+		tServer, err := NewYagoTemplateServer(c)
+		if err != nil {
+			return nil, err
+		}
+		serverOpts = append(serverOpts, WithTemplateServer(tServer))
+	}
+	for _, c := range fc.Files {
+		fServer, err := NewYagoFileServer(c)
+		if err != nil {
+			return nil, err
+		}
+		serverOpts = append(serverOpts, WithFileServer(fServer))
+	}
+	for _, c := range fc.Ws {
+		wServer, err := NewYagoWsServer(c)
+		if err != nil {
+			return nil, err
+		}
+		serverOpts = append(serverOpts, WithWsServer(wServer))
+	}
+
+	return New(append(serverOpts, opts...)...)
+}
+
+// inheritTimeouts sets the server timeout to api and template servers without their own
+func (fc *YagoFileConfig) inheritTimeouts() {
+	for _, c := range fc.Apis {
+		if c.Timeout <= 0 {
+			c.Timeout = int(fc.Server.Timeout)
+		}
+	}
+	for _, c := range fc.Templates {
+		if c.Timeout <= 0 {
+			c.Timeout = int(fc.Server.Timeout)
+		}
+	}
+}
+
+// applyDefaults sets empty fields tagged with default of the structs reachable from v
+func applyDefaults(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return applyDefaults(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := applyDefaults(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if def, ok := f.Tag.Lookup("default"); ok && v.Field(i).IsZero() {
+				if err := setFieldString(v.Field(i), def); err != nil {
+					return fmt.Errorf("invalid default of %s.%s: %s", t.Name(), f.Name, err.Error())
+				}
+				continue
+			}
+			if err := applyDefaults(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyEnv overrides fields of c by env vars named YAGO_ and the upper json name, eg: YAGO_PORT
+func applyEnv(c *YagoConfig) error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		env := envPrefix + strings.ToUpper(name)
+		s, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+		if err := setFieldString(v.Field(i), s); err != nil {
+			return fmt.Errorf("invalid env %s: %s", env, err.Error())
+		}
+	}
+	return nil
+}
+
+func setFieldString(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	default:
+		return errors.New("unsupported field type " + v.Type().String())
+	}
+	return nil
+}
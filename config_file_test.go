@@ -0,0 +1,78 @@
+package yago
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "page.layout"), []byte(`page {{.}}`), 0644))
+
+	files := map[string]string{
+		"yago.json": `{
+			"server": {"timeout": 500},
+			"apis": [{"route": "/a/"}],
+			"templates": [{"route": "/p", "layoutDir": "` + filepath.ToSlash(dir) + `",
+				"pageLayouts": [{"name": "page", "templates": ["page.layout"]}]}],
+			"files": [{"route": "static", "dir": "` + filepath.ToSlash(dir) + `"}]
+		}`,
+		"yago.yaml": `
+server:
+  timeout: 500
+apis:
+  - route: /a/
+templates:
+  - route: /p
+    layoutDir: ` + filepath.ToSlash(dir) + `
+    pageLayouts:
+      - name: page
+        templates: [page.layout]
+files:
+  - route: static
+    dir: ` + filepath.ToSlash(dir) + `
+`,
+	}
+
+	for name, content := range files {
+		name, content := name, content
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(dir, name)
+			assert.Nil(t, os.WriteFile(file, []byte(content), 0644))
+
+			fc, err := LoadConfigFile(file)
+			assert.Nil(t, err, name)
+			assert.Equal(t, uint32(8080), fc.Server.Port, name)
+			assert.Equal(t, uint32(500), fc.Server.Timeout, name)
+
+			t.Setenv("YAGO_PORT", "9090")
+			fc, err = LoadConfigFile(file)
+			assert.Nil(t, err, name)
+			assert.Equal(t, uint32(9090), fc.Server.Port, name)
+
+			y, err := NewFromConfig(fc)
+			assert.Nil(t, err, name)
+			assert.NotNil(t, y.ApiServer("/a/"), name)
+			assert.Equal(t, 500, y.ApiServer("/a/").config().Timeout, name)
+			assert.Nil(t, y.TemplateServer("/p").Register("page", func(ctx *YagoContext) (interface{}, error) { return "yago", nil }), name)
+
+			w := httptest.NewRecorder()
+			y.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/p/page", nil))
+			assert.Equal(t, "page yago", w.Body.String(), name)
+
+			w = httptest.NewRecorder()
+			y.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/page.layout", nil))
+			assert.Equal(t, http.StatusOK, w.Code, name)
+		})
+	}
+
+	t.Setenv("YAGO_PORT", "port")
+	_, err := LoadConfigFile(filepath.Join(dir, "yago.json"))
+	assert.NotNil(t, err)
+}
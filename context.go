@@ -2,6 +2,8 @@ package yago
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 )
@@ -21,12 +23,31 @@ type YagoContext struct {
 	// http Path is equals: route + serviceName
 	serviceName string
 
+	// params are the path params matched by the route pattern,
+	// eg: id of /todo/{id}
+	params map[string]string
+
 	// query is http request query
 	query map[string]string
 
 	// body is http request body
 	body []byte
 
+	// fragment is the template name requested instead of the full page
+	fragment string
+
+	// locale is detected by i18n for the request, empty when no i18n bound
+	locale string
+	i18n   *YagoI18n
+
+	// requestID identifies the request in logs, logger carries it with the route,
+	// service name and remote address
+	requestID string
+	logger    Logger
+
+	// span is the span of the request phase being served, nil when tracing is off
+	span *YagoSpan
+
 	w http.ResponseWriter
 	r *http.Request
 
@@ -38,6 +59,16 @@ func (y *YagoContext) writeResponseStatus(code int) {
 }
 
 func (y *YagoContext) writeJson(data interface{}) {
+	if wrapper, ok := data.(*YagoAPIWrapper); ok {
+		if wrapper.RequestID == "" {
+			wrapper.RequestID = y.requestID
+		}
+		if y.r != nil {
+			if info := requestInfoOf(y.r); info != nil {
+				info.apiCode, info.hasAPICode = wrapper.Code, true
+			}
+		}
+	}
 	bs, _ := json.Marshal(data)
 	y.w.Header().Set("Content-Type", "application/json")
 	y.w.Write(bs)
@@ -54,6 +85,156 @@ func (y *YagoContext) Query(key string) string {
 	return y.query[key]
 }
 
+// Param returns the path param matched by the route pattern
+func (y *YagoContext) Param(key string) string {
+	if y.params == nil {
+		return ""
+	}
+	return y.params[key]
+}
+
 func (y *YagoContext) Path() string {
 	return y.path
 }
+
+// Fragment returns the template name requested instead of the full page,
+// empty when the full page is requested
+func (y *YagoContext) Fragment() string {
+	return y.fragment
+}
+
+// Locale returns the locale detected for the request
+func (y *YagoContext) Locale() string {
+	return y.locale
+}
+
+// T translates key for the locale of the request, see YagoI18n.T
+func (y *YagoContext) T(key string, args ...interface{}) string {
+	return y.i18n.translate(y.locale, key, key, args...)
+}
+
+// message translates key for the locale of the request, def is used when no catalog has key
+func (y *YagoContext) message(key, def string) string {
+	return y.i18n.translate(y.locale, key, def)
+}
+
+// setLocale detects the locale of the request when i18n is bound
+func (y *YagoContext) setLocale(i18n *YagoI18n) {
+	if i18n == nil {
+		return
+	}
+	y.i18n = i18n
+	y.locale = i18n.Detect(y.r)
+}
+
+// RequestID returns the id of the request, see Yago.ServeHTTP
+func (y *YagoContext) RequestID() string {
+	return y.requestID
+}
+
+// Logger returns the logger of the request, records carry the request id, route,
+// service name and remote address of the request
+func (y *YagoContext) Logger() Logger {
+	if y.logger == nil {
+		return nopLogger{}
+	}
+	return y.logger
+}
+
+// Span returns the span of the request phase being served, eg: the handler invoke,
+// it is nil when tracing is off, which every YagoSpan method accepts
+func (y *YagoContext) Span() *YagoSpan {
+	return y.span
+}
+
+// setRequestService reports the service serving the request to Yago
+func (y *YagoContext) setRequestService() {
+	if info := requestInfoOf(y.r); info != nil {
+		info.service = y.serviceName
+	}
+}
+
+func (y *YagoContext) setLogger(logger Logger, requestID string) {
+	y.requestID = requestID
+	kvs := []interface{}{
+		"requestId", requestID,
+		"route", y.route,
+		"service", y.serviceName,
+		"remoteAddr", y.r.RemoteAddr,
+	}
+	if info := requestInfoOf(y.r); info != nil && info.span != nil {
+		y.span = info.span
+		kvs = append(kvs, "traceId", info.span.TraceID())
+	}
+	y.logger = logger.With(kvs...)
+}
+
+const (
+	// RequestIDHeader carries the request id, it is taken from requests and echoed in responses
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLen = 128
+)
+
+// yagoRequestInfo is bound to the context of a request by Yago.ServeHTTP, servers
+// report what Yago can not see in the response back through it, eg: api codes
+type yagoRequestInfo struct {
+	id string
+
+	// service is the service name of a request served by a registered service,
+	// never a raw path, so it is safe as a metrics label
+	service string
+
+	apiCode    int
+	hasAPICode bool
+
+	// span is the server span of the request when tracing is on
+	span *YagoSpan
+}
+
+type requestInfoKey struct{}
+
+// withRequestInfo binds a new request info to the context of r, unless r has one
+func withRequestInfo(r *http.Request) (*http.Request, *yagoRequestInfo) {
+	if info := requestInfoOf(r); info != nil {
+		return r, info
+	}
+	info := &yagoRequestInfo{id: incomingRequestID(r)}
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)), info
+}
+
+func requestInfoOf(r *http.Request) *yagoRequestInfo {
+	info, _ := r.Context().Value(requestInfoKey{}).(*yagoRequestInfo)
+	return info
+}
+
+// requestIDOf returns the request id bound by Yago.ServeHTTP, servers serving
+// requests without Yago take it from the request header or get a new one
+func requestIDOf(r *http.Request) string {
+	if info := requestInfoOf(r); info != nil {
+		return info.id
+	}
+	return incomingRequestID(r)
+}
+
+// incomingRequestID returns the X-Request-ID of r set by a proxy or the caller, a new id
+// when it is missing or unsafe to write to logs
+func incomingRequestID(r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDLen {
+		return newRequestID()
+	}
+	for i := 0; i < len(id); i++ {
+		// printable ascii without spaces and quotes, so ids never break log records
+		if id[i] <= ' ' || id[i] > '~' || id[i] == '"' {
+			return newRequestID()
+		}
+	}
+	return id
+}
+
+func newRequestID() string {
+	bs := make([]byte, 8)
+	rand.Read(bs)
+	return hex.EncodeToString(bs)
+}
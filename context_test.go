@@ -0,0 +1,67 @@
+package yago
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+
+	aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/a/", Timeout: 1000})
+	var handled string
+	assert.Nil(t, aServer.Register("apidemo", func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) {
+		handled = ctx.RequestID()
+		return &DemoRsp{}, nil
+	}))
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithApiServer(aServer))
+	assert.Nil(t, err)
+
+	uts := []struct {
+		header string
+		// expect is the id expected, empty expects a new one
+		expect string
+	}{
+		{header: "", expect: ""},
+		{header: "req-0b1c/2", expect: "req-0b1c/2"},
+		{header: "a b", expect: ""},
+		{header: "a\"b", expect: ""},
+		{header: "a\nb", expect: ""},
+		{header: strings.Repeat("a", 129), expect: ""},
+	}
+
+	for _, ut := range uts {
+		r := httptest.NewRequest(http.MethodPost, "/a/apidemo", strings.NewReader(`{}`))
+		if ut.header != "" {
+			r.Header.Set(RequestIDHeader, ut.header)
+		}
+		w := httptest.NewRecorder()
+		y.ServeHTTP(w, r)
+
+		id := w.Header().Get(RequestIDHeader)
+		if ut.expect != "" {
+			assert.Equal(t, ut.expect, id)
+		} else {
+			assert.Regexp(t, `^[0-9a-f]{16}$`, id, ut.header)
+		}
+		assert.Equal(t, id, handled)
+
+		rsp := &YagoAPIWrapper{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), rsp))
+		assert.Equal(t, id, rsp.RequestID)
+	}
+
+	// failed requests carry the id too, and requests without Yago take the header
+	r := httptest.NewRequest(http.MethodPost, "/a/missing", strings.NewReader(`{}`))
+	r.Header.Set(RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	aServer.ServeHTTP(w, r)
+	rsp := &YagoAPIWrapper{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), rsp))
+	assert.Equal(t, CodeYagoAPIServiceNotFound, rsp.Code)
+	assert.Equal(t, "req-1", rsp.RequestID)
+}
@@ -4,7 +4,6 @@ go 1.19
 
 require github.com/graceful-go/yago v0.0.1
 
-require (
-	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db // indirect
-	github.com/syndtr/goleveldb v1.0.0 // indirect
-)
+require gopkg.in/yaml.v3 v3.0.1 // indirect
+
+replace github.com/graceful-go/yago => ../
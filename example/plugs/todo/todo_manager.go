@@ -2,4 +2,4 @@ package todo
 
 type TodoManager struct{}
 
-func (t *TodoManager) Get()
+func (t *TodoManager) Get() {}
@@ -0,0 +1,220 @@
+package yago
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// YagoExportParams enumerates path params of a page to export, one map for each page
+type YagoExportParams func() ([]map[string]string, error)
+
+// YagoExportConfig
+// YagoExportConfig is used to export template pages and static files as a static site
+type YagoExportConfig struct {
+	// OutDir is the local directory the site is written to
+	OutDir string `json:"outDir"`
+
+	// Params enumerates path params by service name, pages with params and
+	// without Params are skipped
+	Params map[string]YagoExportParams `json:"-"`
+}
+
+// yagoExporter is implemented by handlers which can be exported as static files
+type yagoExporter interface {
+	export(ctx context.Context, c *YagoExportConfig, site *yagoExportSite) error
+}
+
+// yagoExportSite collects pages before writing, so that links between pages
+// can be rewritten once every exported page is known
+type yagoExportSite struct {
+	pages map[string][]byte
+	files map[string]bool
+}
+
+// Export walks every template server and file server and writes the rendered pages
+// as <path>/index.html and the static files into OutDir, absolute links in pages are
+// rewritten to relative ones so the site can be browsed from any directory
+func (y *Yago) Export(ctx context.Context, c *YagoExportConfig) error {
+
+	if c == nil || c.OutDir == "" {
+		return errors.New("empty export config is not allowed")
+	}
+
+	site := &yagoExportSite{pages: make(map[string][]byte), files: make(map[string]bool)}
+	for _, h := range y.handlerList() {
+		e, ok := h.(yagoExporter)
+		if !ok {
+			continue
+		}
+		if err := e.export(ctx, c, site); err != nil {
+			return err
+		}
+	}
+
+	for p, body := range site.pages {
+		file := exportPageFile(p)
+		body = site.relativeLinks(path.Dir(file), body)
+		if err := writeExportFile(c.OutDir, file, body); err != nil {
+			return err
+		}
+	}
+
+	y.logger.Info("[Yago] Export succ", "pages", len(site.pages), "files", len(site.files), "outDir", c.OutDir)
+	return nil
+}
+
+func (y *YagoTemplateServer) export(ctx context.Context, c *YagoExportConfig, site *yagoExportSite) error {
+
+	y.mu.RLock()
+	routes := append([]*yagoRoute{}, y.router.routes...)
+	y.mu.RUnlock()
+
+	sort.Slice(routes, func(i, j int) bool { return routes[i].pattern < routes[j].pattern })
+
+	for _, r := range routes {
+		paramsList := []map[string]string{{}}
+		if strings.Contains(r.pattern, "{") {
+			enum, ok := c.Params[r.name]
+			if !ok {
+				y.logger.Warn("[YagoTemplateServer] Export skip page without params", "service", r.name, "pattern", r.pattern)
+				continue
+			}
+			list, err := enum()
+			if err != nil {
+				return fmt.Errorf("export page %s fail: %s", r.name, err.Error())
+			}
+			paramsList = list
+		}
+
+		for _, params := range paramsList {
+			p, err := r.build(params)
+			if err != nil {
+				return fmt.Errorf("export page %s fail: %s", r.name, err.Error())
+			}
+			// dot segments would write the page over another page or outside OutDir
+			for _, seg := range strings.Split(p, "/") {
+				if seg == "." || seg == ".." {
+					return fmt.Errorf("export page %s fail: invalid path %s", r.name, p)
+				}
+			}
+			body, err := y.exportPage(ctx, p)
+			if err != nil {
+				return err
+			}
+			site.pages[p] = body
+		}
+	}
+	return nil
+}
+
+func (y *YagoTemplateServer) exportPage(ctx context.Context, p string) ([]byte, error) {
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, p, nil)
+	if err != nil {
+		return nil, err
+	}
+	w := &yagoExportWriter{header: make(http.Header), status: http.StatusOK}
+	y.ServeHTTP(w, r)
+	if w.status != http.StatusOK {
+		return nil, fmt.Errorf("export page %s fail with status %d", p, w.status)
+	}
+	return w.body.Bytes(), nil
+}
+
+func (y *YagoFileServer) export(ctx context.Context, c *YagoExportConfig, site *yagoExportSite) error {
+	return fs.WalkDir(y.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		bs, err := fs.ReadFile(y.fsys, p)
+		if err != nil {
+			return err
+		}
+		for _, name := range []string{p, y.manifest[p]} {
+			if name == "" {
+				continue
+			}
+			file := path.Join(strings.Trim(y.fsPath, "/"), name)
+			site.files["/"+file] = true
+			if err := writeExportFile(c.OutDir, file, bs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// yagoExportWriter records a response rendered for export
+type yagoExportWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *yagoExportWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *yagoExportWriter) Write(bs []byte) (int, error) {
+	return w.body.Write(bs)
+}
+
+func (w *yagoExportWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// exportPageFile maps a page path to its file, eg: / => index.html, /todo => todo/index.html
+func exportPageFile(p string) string {
+	return path.Join(strings.Trim(p, "/"), "index.html")
+}
+
+var exportLinkPattern = regexp.MustCompile(`(\s(?:href|src|action|poster)=)(["'])(/(?:[^/"'][^"']*)?)(["'])`)
+
+// relativeLinks rewrites absolute links of a page in dir relative to dir,
+// links to exported pages point to their index.html
+func (s *yagoExportSite) relativeLinks(dir string, body []byte) []byte {
+	return exportLinkPattern.ReplaceAllFunc(body, func(m []byte) []byte {
+		sub := exportLinkPattern.FindSubmatch(m)
+		link, suffix := string(sub[3]), ""
+		if i := strings.IndexAny(link, "?#"); i >= 0 {
+			link, suffix = link[:i], link[i:]
+		}
+
+		target := strings.TrimPrefix(link, "/")
+		if _, ok := s.pages[path.Clean(link)]; ok && !s.files[link] {
+			target = exportPageFile(link)
+		}
+
+		rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(target))
+		if err != nil {
+			return m
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasSuffix(link, "/") && !strings.HasSuffix(rel, "/") && !strings.HasSuffix(rel, ".html") {
+			rel += "/"
+		}
+
+		return []byte(string(sub[1]) + string(sub[2]) + rel + suffix + string(sub[4]))
+	})
+}
+
+func writeExportFile(outDir, file string, bs []byte) error {
+	dst := filepath.Join(outDir, filepath.FromSlash(file))
+	if rel, err := filepath.Rel(outDir, dst); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("export file %s is outside %s", file, outDir)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, bs, 0644)
+}
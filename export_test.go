@@ -0,0 +1,104 @@
+package yago
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoExport(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"layout/page.layout":  {Data: []byte(`<link href="/static/css/base.css"><a href="/">home</a><a href='/docs/{{.}}?x=1'>{{.}}</a><a href="https://a.com/">ext</a>`)},
+		"assets/css/base.css": {Data: []byte(`body{}`)},
+	}
+
+	tServer, _ := NewYagoTemplateServer(&YagoTemplateConfig{
+		Route:     "/",
+		FS:        fsys,
+		LayoutDir: "layout",
+		PageLayouts: []*PageLayoutConfig{
+			{ServiceName: "index", Path: "/", Templates: []string{"page.layout"}},
+			{ServiceName: "doc", Path: "/docs/{name}", Templates: []string{"page.layout"}},
+			{ServiceName: "search", Path: "/search/{q}", Templates: []string{"page.layout"}},
+		},
+	})
+	hd := func(ctx *YagoContext) (interface{}, error) { return "intro", nil }
+	assert.Nil(t, tServer.Register("index", hd))
+	assert.Nil(t, tServer.Register("doc", hd))
+	assert.Nil(t, tServer.Register("search", hd))
+	fServer, _ := NewYagoFileServer(&YagoFileServerConfig{FS: fsys, Dir: "assets", Route: "static"})
+
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithTemplateServer(tServer), WithFileServer(fServer))
+	assert.Nil(t, err)
+
+	out := t.TempDir()
+	assert.Nil(t, y.Export(context.Background(), &YagoExportConfig{
+		OutDir: out,
+		Params: map[string]YagoExportParams{
+			"doc": func() ([]map[string]string, error) { return []map[string]string{{"name": "intro"}}, nil },
+		},
+	}))
+
+	bs, err := os.ReadFile(filepath.Join(out, "index.html"))
+	assert.Nil(t, err)
+	assert.Equal(t, `<link href="static/css/base.css"><a href="index.html">home</a><a href='docs/intro/index.html?x=1'>intro</a><a href="https://a.com/">ext</a>`, string(bs))
+
+	bs, err = os.ReadFile(filepath.Join(out, "docs", "intro", "index.html"))
+	assert.Nil(t, err)
+	assert.Equal(t, `<link href="../../static/css/base.css"><a href="../../index.html">home</a><a href='index.html?x=1'>intro</a><a href="https://a.com/">ext</a>`, string(bs))
+
+	bs, err = os.ReadFile(filepath.Join(out, "static", "css", "base.css"))
+	assert.Nil(t, err)
+	assert.Equal(t, "body{}", string(bs))
+
+	_, err = os.Stat(filepath.Join(out, "search"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestYagoExportDotSegments(t *testing.T) {
+
+	tServer, _ := NewYagoTemplateServer(&YagoTemplateConfig{
+		Route: "/",
+		FS:    fstest.MapFS{"page.layout": {Data: []byte(`page`)}},
+		PageLayouts: []*PageLayoutConfig{
+			{ServiceName: "doc", Path: "/docs/{name}", Templates: []string{"page.layout"}},
+			{ServiceName: "file", Path: "/files/{path...}", Templates: []string{"page.layout"}},
+		},
+	})
+	hd := func(ctx *YagoContext) (interface{}, error) { return nil, nil }
+	assert.Nil(t, tServer.Register("doc", hd))
+	assert.Nil(t, tServer.Register("file", hd))
+
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithLogger(nopLogger{}), WithTemplateServer(tServer))
+	assert.Nil(t, err)
+
+	var uts = []struct {
+		Service string
+		Params  map[string]string
+	}{
+		{Service: "doc", Params: map[string]string{"name": ".."}},
+		{Service: "doc", Params: map[string]string{"name": "."}},
+		{Service: "file", Params: map[string]string{"path": "../../escaped"}},
+	}
+	for _, ut := range uts {
+		out := filepath.Join(t.TempDir(), "site")
+		params := []map[string]string{ut.Params}
+		err := y.Export(context.Background(), &YagoExportConfig{
+			OutDir: out,
+			Params: map[string]YagoExportParams{ut.Service: func() ([]map[string]string, error) { return params, nil }},
+		})
+		assert.ErrorContains(t, err, "invalid path", ut.Params)
+
+		_, err = os.Stat(filepath.Dir(out))
+		assert.Nil(t, err)
+		_, err = os.Stat(out)
+		assert.True(t, os.IsNotExist(err), ut.Params)
+	}
+
+	assert.NotNil(t, writeExportFile(t.TempDir(), "../escaped/index.html", []byte(`page`)))
+}
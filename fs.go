@@ -0,0 +1,21 @@
+package yago
+
+import (
+	"io/fs"
+	"os"
+)
+
+// subFS resolves the file system a sub-server reads from,
+// the OS directory dir is used when fsys is nil, otherwise dir is a sub directory of fsys
+func subFS(fsys fs.FS, dir string) (fs.FS, error) {
+	if fsys == nil {
+		if dir == "" {
+			dir = "."
+		}
+		return os.DirFS(dir), nil
+	}
+	if dir == "" || dir == "." {
+		return fsys, nil
+	}
+	return fs.Sub(fsys, dir)
+}
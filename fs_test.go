@@ -0,0 +1,41 @@
+package yago
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoServersWithFS(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"layout/page.layout": {Data: []byte(`{{template "base" .}}`)},
+		"layout/base.layout": {Data: []byte(`{{define "base"}}hello {{.}}{{end}}`)},
+		"assets/css/a.css":   {Data: []byte(`body{}`)},
+	}
+
+	ts, err := NewYagoTemplateServer(&YagoTemplateConfig{
+		Route:       "/p",
+		FS:          fsys,
+		LayoutDir:   "layout",
+		BaseLayouts: []string{"base.layout"},
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "page", Templates: []string{"page.layout"}}},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, ts.Register("page", func(ctx *YagoContext) (interface{}, error) { return "yago", nil }))
+
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/p/page", nil))
+	assert.Equal(t, "hello yago", w.Body.String())
+
+	fServer, err := NewYagoFileServer(&YagoFileServerConfig{FS: fsys, Dir: "assets", Route: "static"})
+	assert.Nil(t, err)
+
+	w = httptest.NewRecorder()
+	fServer.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/css/a.css", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "body{}", w.Body.String())
+}
@@ -0,0 +1,222 @@
+package yago
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultFuncs returns the builtin template functions bound to every YagoTemplateServer,
+// functions taking a value put it last so that they can be used in pipelines,
+// eg: {{.CreateTime | unix | date "2006-01-02"}}
+func DefaultFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		// date and time
+		"now":    time.Now,
+		"unix":   funcUnix,
+		"date":   funcDate,
+		"dateIn": funcDateIn,
+
+		// numbers
+		"number":      funcNumber,
+		"formatBytes": funcFormatBytes,
+
+		// strings
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"title":     funcTitle,
+		"trim":      strings.TrimSpace,
+		"truncate":  funcTruncate,
+		"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"replace":   func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"split":     func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":      funcJoin,
+		"default":   funcDefault,
+
+		// data
+		"json": funcJSON,
+		"dict": funcDict,
+		"list": funcList,
+	}
+}
+
+func toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, nil
+		}
+		return *t, nil
+	case int64:
+		return time.Unix(t, 0), nil
+	case int:
+		return time.Unix(int64(t), 0), nil
+	}
+	return time.Time{}, fmt.Errorf("can not format %T as time", v)
+}
+
+// funcUnix converts unix seconds to time
+func funcUnix(ts int64) time.Time {
+	return time.Unix(ts, 0)
+}
+
+// funcDate formats a time.Time or unix seconds in local time zone
+func funcDate(layout string, v interface{}) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(layout), nil
+}
+
+// funcDateIn formats a time.Time or unix seconds in the IANA time zone tz, eg: Asia/Shanghai
+func funcDateIn(layout, tz string, v interface{}) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return "", err
+	}
+	return t.In(loc).Format(layout), nil
+}
+
+// funcNumber formats n with thousands separators and the given decimals, eg: 1,234.50
+func funcNumber(decimals int, n interface{}) (string, error) {
+	f, err := toFloat(n)
+	if err != nil {
+		return "", err
+	}
+
+	s := strconv.FormatFloat(math.Abs(f), 'f', decimals, 64)
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i:]
+	}
+
+	var b strings.Builder
+	if f < 0 {
+		b.WriteByte('-')
+	}
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteString(fracPart)
+	return b.String(), nil
+}
+
+// funcFormatBytes formats a size in bytes with binary units, eg: 1.5 KB
+func funcFormatBytes(n interface{}) (string, error) {
+	f, err := toFloat(n)
+	if err != nil {
+		return "", err
+	}
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	i := 0
+	for math.Abs(f) >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", int64(f)), nil
+	}
+	return strconv.FormatFloat(f, 'f', 1, 64) + " " + units[i], nil
+}
+
+func toFloat(n interface{}) (float64, error) {
+	v := reflect.ValueOf(n)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	}
+	return 0, fmt.Errorf("can not format %T as number", n)
+}
+
+func funcTitle(s string) string {
+	rs := []rune(s)
+	for i := range rs {
+		if i == 0 || unicode.IsSpace(rs[i-1]) {
+			rs[i] = unicode.ToTitle(rs[i])
+		}
+	}
+	return string(rs)
+}
+
+// funcTruncate cuts s to n runes and appends ... when it is cut
+func funcTruncate(n int, s string) string {
+	rs := []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+	return string(rs[:n]) + "..."
+}
+
+func funcJoin(sep string, v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("can not join %T", v)
+	}
+	items := make([]string, rv.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(items, sep), nil
+}
+
+// funcDefault returns def when v is the zero value
+func funcDefault(def, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	if rv := reflect.ValueOf(v); rv.IsZero() {
+		return def
+	}
+	return v
+}
+
+// funcJSON marshals v for embedding in <script>, html special chars are escaped by encoding/json
+func funcJSON(v interface{}) (template.JS, error) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(bs), nil
+}
+
+func funcDict(kvs ...interface{}) (map[string]interface{}, error) {
+	if len(kvs)%2 != 0 {
+		return nil, errors.New("dict requires key value pairs")
+	}
+	m := make(map[string]interface{}, len(kvs)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		k, ok := kvs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict key must be string, got %v", kvs[i])
+		}
+		m[k] = kvs[i+1]
+	}
+	return m, nil
+}
+
+func funcList(items ...interface{}) []interface{} {
+	return items
+}
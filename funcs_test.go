@@ -0,0 +1,40 @@
+package yago
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultFuncs(t *testing.T) {
+
+	ts := time.Date(2024, 3, 1, 16, 30, 0, 0, time.UTC)
+
+	var uts = []struct {
+		Tmpl   string
+		Data   interface{}
+		Expect string
+	}{
+		{Tmpl: `{{. | date "2006-01-02 15:04"}}`, Data: ts, Expect: "2024-03-01 16:30"},
+		{Tmpl: `{{. | dateIn "15:04" "Asia/Shanghai"}}`, Data: ts.Unix(), Expect: "00:30"},
+		{Tmpl: `{{. | number 2}}`, Data: -1234567.891, Expect: "-1,234,567.89"},
+		{Tmpl: `{{. | number 0}}`, Data: 999, Expect: "999"},
+		{Tmpl: `{{. | formatBytes}}`, Data: 1536, Expect: "1.5 KB"},
+		{Tmpl: `{{. | formatBytes}}`, Data: int64(12), Expect: "12 B"},
+		{Tmpl: `{{. | title}}`, Data: "hello yago", Expect: "Hello Yago"},
+		{Tmpl: `{{. | truncate 4}}`, Data: "办公助手工作台", Expect: "办公助手..."},
+		{Tmpl: `{{. | join ","}}`, Data: []int{1, 2}, Expect: "1,2"},
+		{Tmpl: `{{. | default "none"}}`, Data: "", Expect: "none"},
+		{Tmpl: `<script>var d = {{json .}};</script>`, Data: map[string]string{"a": "</script>"}, Expect: `<script>var d = {"a":"\u003c/script\u003e"};</script>`},
+		{Tmpl: `{{with dict "a" 1 "b" (list 1 2)}}{{.a}}{{len .b}}{{end}}`, Data: nil, Expect: "12"},
+	}
+	for _, uc := range uts {
+		tmpl := template.Must(template.New("").Funcs(DefaultFuncs()).Parse(uc.Tmpl))
+		buf := &bytes.Buffer{}
+		assert.Nil(t, tmpl.Execute(buf, uc.Data), uc.Tmpl)
+		assert.Equal(t, uc.Expect, buf.String(), uc.Tmpl)
+	}
+}
@@ -0,0 +1,260 @@
+package yago
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLiveRoute          = "/healthz"
+	defaultReadyRoute         = "/readyz"
+	defaultHealthCheckTimeout = 1000
+
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+)
+
+// YagoHealthConfig
+// YagoHealthConfig serves the liveness and readiness probes of Yago, eg: for Kubernetes.
+// Readiness fails until every template page is parsed and once graceful shutdown begins
+type YagoHealthConfig struct {
+	// LiveRoute is the path of the liveness probe, default /healthz
+	LiveRoute string `json:"liveRoute"`
+
+	// ReadyRoute is the path of the readiness probe, default /readyz
+	ReadyRoute string `json:"readyRoute"`
+
+	// Timeout is the default timeout in milliseconds of each check, default 1000
+	Timeout int `json:"timeout"`
+
+	// ShutdownDelay is how long in milliseconds readiness fails before the server stops
+	// accepting requests on shutdown, so load balancers stop routing to it first
+	ShutdownDelay int `json:"shutdownDelay"`
+}
+
+// YagoHealthCheck reports the health of a dependency, eg: a database ping, ctx is done
+// once the timeout of the check is reached
+type YagoHealthCheck func(ctx context.Context) error
+
+// yagoReadyChecker is implemented by handlers which are not ready right after startup,
+// eg: template servers until every page is parsed
+type yagoReadyChecker interface {
+	ready() error
+}
+
+type yagoHealthCheck struct {
+	name    string
+	timeout time.Duration
+	check   YagoHealthCheck
+}
+
+type YagoHealth struct {
+	c    *YagoHealthConfig
+	yago *Yago
+
+	mu     sync.RWMutex
+	live   []*yagoHealthCheck
+	checks []*yagoHealthCheck
+}
+
+// yagoHealthResult is the JSON body of probes
+type yagoHealthResult struct {
+	Status string                   `json:"status"`
+	Checks []*yagoHealthCheckResult `json:"checks"`
+}
+
+type yagoHealthCheckResult struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"durationMs"`
+}
+
+func NewYagoHealth(c *YagoHealthConfig) (*YagoHealth, error) {
+	if c == nil {
+		return nil, errors.New("empty health config is not allowed")
+	}
+	if c.LiveRoute == "" {
+		c.LiveRoute = defaultLiveRoute
+	}
+	if c.ReadyRoute == "" {
+		c.ReadyRoute = defaultReadyRoute
+	}
+	if c.LiveRoute == c.ReadyRoute {
+		return nil, errors.New("health live route and ready route must differ: " + c.LiveRoute)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultHealthCheckTimeout
+	}
+	return &YagoHealth{c: c}, nil
+}
+
+// AddCheck adds a named readiness check, a timeout of 0 uses the config Timeout
+func (y *YagoHealth) AddCheck(name string, timeout time.Duration, check YagoHealthCheck) error {
+	return y.add(&y.checks, name, timeout, check)
+}
+
+// AddLiveCheck adds a named liveness check, failing it restarts the process on Kubernetes,
+// so it should only check what a restart fixes, eg: a deadlock
+func (y *YagoHealth) AddLiveCheck(name string, timeout time.Duration, check YagoHealthCheck) error {
+	return y.add(&y.live, name, timeout, check)
+}
+
+func (y *YagoHealth) add(checks *[]*yagoHealthCheck, name string, timeout time.Duration, check YagoHealthCheck) error {
+	if name == "" || check == nil {
+		return errors.New("health check needs a name and a check func")
+	}
+	if timeout <= 0 {
+		timeout = time.Millisecond * time.Duration(y.c.Timeout)
+	}
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	for _, c := range *checks {
+		if c.name == name {
+			return errors.New("duplicate health check registed: " + name)
+		}
+	}
+	*checks = append(*checks, &yagoHealthCheck{name: name, timeout: timeout, check: check})
+	return nil
+}
+
+// handlers returns a handler for each probe route
+func (y *YagoHealth) handlers() []YagoHandler {
+	return []YagoHandler{
+		&yagoHealthProbe{health: y, route: y.c.LiveRoute},
+		&yagoHealthProbe{health: y, route: y.c.ReadyRoute, ready: true},
+	}
+}
+
+func (y *YagoHealth) serve(w http.ResponseWriter, r *http.Request, ready bool) {
+
+	y.mu.RLock()
+	checks := y.live
+	if ready {
+		checks = append(y.builtinChecks(), y.checks...)
+	}
+	y.mu.RUnlock()
+
+	result := &yagoHealthResult{Status: healthStatusOK, Checks: make([]*yagoHealthCheckResult, len(checks))}
+	wg := sync.WaitGroup{}
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *yagoHealthCheck) {
+			defer wg.Done()
+			result.Checks[i] = runHealthCheck(r.Context(), c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	for _, c := range result.Checks {
+		if c.Status != healthStatusOK {
+			result.Status, status = healthStatusFail, http.StatusServiceUnavailable
+		}
+	}
+
+	bs, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(bs)
+}
+
+// builtinChecks fail readiness during shutdown and until every handler is ready
+func (y *YagoHealth) builtinChecks() []*yagoHealthCheck {
+
+	timeout := time.Millisecond * time.Duration(y.c.Timeout)
+	yago := y.yago
+	return []*yagoHealthCheck{
+		{name: "shutdown", timeout: timeout, check: func(ctx context.Context) error {
+			if yago != nil && yago.shuttingDown.Load() {
+				return errors.New("server is shutting down")
+			}
+			return nil
+		}},
+		{name: "handlers", timeout: timeout, check: func(ctx context.Context) error {
+			if yago == nil {
+				return nil
+			}
+			for _, h := range yago.handlerList() {
+				if r, ok := h.(yagoReadyChecker); ok {
+					if err := r.ready(); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}},
+	}
+}
+
+// runHealthCheck runs c until its timeout, a check ignoring ctx is abandoned on timeout
+func runHealthCheck(ctx context.Context, c *yagoHealthCheck) *yagoHealthCheckResult {
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- fmt.Errorf("panic: %v", v)
+			}
+		}()
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timeout after %s", c.timeout)
+	}
+
+	result := &yagoHealthCheckResult{
+		Name:     c.name,
+		Status:   healthStatusOK,
+		Duration: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status, result.Error = healthStatusFail, err.Error()
+	}
+	return result
+}
+
+// yagoHealthProbe serves a probe route of YagoHealth
+type yagoHealthProbe struct {
+	health *YagoHealth
+	route  string
+	ready  bool
+}
+
+func (y *yagoHealthProbe) Handler() http.Handler {
+	return y
+}
+
+func (y *yagoHealthProbe) Pattern() string {
+	return y.route
+}
+
+func (y *yagoHealthProbe) Type() string {
+	return "YagoHealth"
+}
+
+func (y *yagoHealthProbe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != y.route {
+		http.NotFound(w, r)
+		return
+	}
+	y.health.serve(w, r, y.ready)
+}
+
+func (y *yagoHealthProbe) bind(yago *Yago) {
+	y.health.yago = yago
+}
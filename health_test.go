@@ -0,0 +1,159 @@
+package yago
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveHealth(t *testing.T, y *Yago, path string) (int, *yagoHealthResult) {
+	w := httptest.NewRecorder()
+	y.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	result := &yagoHealthResult{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), result))
+	return w.Code, result
+}
+
+func TestYagoHealth(t *testing.T) {
+
+	tServer := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/",
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "index", Path: "/", Templates: []string{"page.layout"}}},
+	}, fstest.MapFS{"page.layout": {Data: []byte(`index`)}})
+
+	health, err := NewYagoHealth(&YagoHealthConfig{Timeout: 50})
+	assert.Nil(t, err)
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithTemplateServer(tServer), WithHealth(health))
+	assert.Nil(t, err)
+	assert.Equal(t, health, y.Health())
+
+	// readiness waits for the templates of every page
+	status, result := serveHealth(t, y, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "handlers", result.Checks[1].Name)
+	assert.Equal(t, "templates of index on / are not registered", result.Checks[1].Error)
+
+	assert.Nil(t, tServer.Register("index", func(ctx *YagoContext) (interface{}, error) { return nil, nil }))
+	status, result = serveHealth(t, y, "/readyz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", result.Status)
+
+	uts := []struct {
+		name  string
+		check YagoHealthCheck
+		err   string
+	}{
+		{name: "db", check: func(ctx context.Context) error { return nil }},
+		{name: "cache", check: func(ctx context.Context) error { return errors.New("connection refused") }, err: "connection refused"},
+		{name: "slow", check: func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }, err: "timeout after 50ms"},
+		{name: "broken", check: func(ctx context.Context) error { panic("boom") }, err: "panic: boom"},
+	}
+	for _, ut := range uts {
+		assert.Nil(t, health.AddCheck(ut.name, 0, ut.check))
+	}
+	assert.NotNil(t, health.AddCheck("db", 0, uts[0].check))
+
+	status, result = serveHealth(t, y, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "fail", result.Status)
+	assert.Len(t, result.Checks, 2+len(uts))
+	for i, ut := range uts {
+		c := result.Checks[2+i]
+		assert.Equal(t, ut.name, c.Name)
+		assert.Equal(t, ut.err, c.Error, ut.name)
+		if ut.err == "" {
+			assert.Equal(t, "ok", c.Status)
+		} else {
+			assert.Equal(t, "fail", c.Status)
+		}
+	}
+
+	// liveness only runs live checks
+	status, result = serveHealth(t, y, "/healthz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, result.Checks)
+
+	assert.Nil(t, health.AddLiveCheck("loop", time.Second, func(ctx context.Context) error { return nil }))
+	status, result = serveHealth(t, y, "/healthz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "loop", result.Checks[0].Name)
+
+	_, err = NewYagoHealth(&YagoHealthConfig{LiveRoute: "/probe", ReadyRoute: "/probe"})
+	assert.NotNil(t, err)
+}
+
+func TestYagoGracefulShutdown(t *testing.T) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	health, _ := NewYagoHealth(&YagoHealthConfig{ShutdownDelay: 100})
+	y, err := New(WithConfig(&YagoConfig{Port: uint32(port)}), WithLogger(nopLogger{}), WithHealth(health))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() {
+		started <- y.Start(ctx)
+	}()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/readyz", port)
+	assert.Eventually(t, func() bool {
+		rsp, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		rsp.Body.Close()
+		return rsp.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	// readiness fails during the shutdown delay while requests are still served
+	assert.Eventually(t, func() bool {
+		rsp, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		rsp.Body.Close()
+		return rsp.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-started:
+		assert.Nil(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Start did not return after ctx is done")
+	}
+}
+
+func TestYagoHealthTemplateError(t *testing.T) {
+
+	// DevMode registers pages failing to parse, so the error page shows the error
+	tServer := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/",
+		DevMode:     true,
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "index", Path: "/", Templates: []string{"page.layout"}}},
+	}, fstest.MapFS{"page.layout": {Data: []byte(`{{.Broken`)}})
+	assert.Nil(t, tServer.Register("index", func(ctx *YagoContext) (interface{}, error) { return nil, nil }))
+
+	health, _ := NewYagoHealth(&YagoHealthConfig{})
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithTemplateServer(tServer), WithHealth(health))
+	assert.Nil(t, err)
+
+	status, result := serveHealth(t, y, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "handlers", result.Checks[1].Name)
+	assert.Contains(t, result.Checks[1].Error, "templates of index on / fail to parse: template: page.layout")
+}
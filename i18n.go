@@ -0,0 +1,350 @@
+package yago
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// YagoI18nConfig
+// YagoI18nConfig loads message catalogs named by locale, eg: en.json, zh-CN.po.
+// A json catalog maps keys to messages, a plural message maps plural categories to messages:
+//
+//	{"hello": "Hello {name}", "items": {"one": "{count} item", "other": "{count} items"}}
+//
+// A gettext po catalog uses msgid as key and msgstr[n] in the order of plural categories of the locale
+type YagoI18nConfig struct {
+	// Dir is the local directory of catalogs, when FS is set Dir is the sub directory inside FS
+	Dir string `json:"dir"`
+
+	// FS serves catalogs instead of the OS directory, eg: an embed.FS
+	FS fs.FS `json:"-"`
+
+	// DefaultLocale is used when no locale of a request has a catalog, default en
+	DefaultLocale string `json:"defaultLocale"`
+
+	// QueryParam and CookieName select the locale of a request before Accept-Language, default lang
+	QueryParam string `json:"queryParam"`
+	CookieName string `json:"cookieName"`
+}
+
+type YagoI18n struct {
+	c        *YagoI18nConfig
+	catalogs map[string]map[string]*yagoI18nMessage
+}
+
+// yagoI18nMessage holds messages by plural category, a singular message only has other
+type yagoI18nMessage struct {
+	forms map[string]string
+}
+
+func NewYagoI18n(c *YagoI18nConfig) (*YagoI18n, error) {
+
+	if c == nil {
+		return nil, errors.New("empty i18n config is not allowed")
+	}
+
+	fsys, err := subFS(c.FS, c.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	y := &YagoI18n{c: c, catalogs: make(map[string]map[string]*yagoI18nMessage)}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		ext := path.Ext(e.Name())
+		if e.IsDir() || (ext != ".json" && ext != ".po") {
+			continue
+		}
+		bs, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		locale := normalizeLocale(strings.TrimSuffix(e.Name(), ext))
+
+		var catalog map[string]*yagoI18nMessage
+		if ext == ".json" {
+			catalog, err = parseJSONCatalog(bs)
+		} else {
+			catalog, err = parsePOCatalog(bs, pluralCategories(locale))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("load catalog %s fail: %s", e.Name(), err.Error())
+		}
+
+		if y.catalogs[locale] == nil {
+			y.catalogs[locale] = catalog
+			continue
+		}
+		for k, v := range catalog {
+			y.catalogs[locale][k] = v
+		}
+	}
+
+	return y, nil
+}
+
+// Locales returns locales having a catalog
+func (y *YagoI18n) Locales() []string {
+	locales := make([]string, 0, len(y.catalogs))
+	for k := range y.catalogs {
+		locales = append(locales, k)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+func (y *YagoI18n) defaultLocale() string {
+	if y.c.DefaultLocale == "" {
+		return "en"
+	}
+	return normalizeLocale(y.c.DefaultLocale)
+}
+
+// Detect returns the locale of a request from query, cookie and Accept-Language in order,
+// only locales having a catalog are selected
+func (y *YagoI18n) Detect(r *http.Request) string {
+
+	queryParam, cookieName := y.c.QueryParam, y.c.CookieName
+	if queryParam == "" {
+		queryParam = "lang"
+	}
+	if cookieName == "" {
+		cookieName = "lang"
+	}
+
+	if l, ok := y.match(r.URL.Query().Get(queryParam)); ok {
+		return l
+	}
+	if c, err := r.Cookie(cookieName); err == nil {
+		if l, ok := y.match(c.Value); ok {
+			return l
+		}
+	}
+	for _, v := range parseAcceptLanguage(r.Header.Get("Accept-Language")) {
+		if l, ok := y.match(v); ok {
+			return l
+		}
+	}
+	return y.defaultLocale()
+}
+
+// match returns the catalog locale for a language tag, falling back to its base language
+func (y *YagoI18n) match(tag string) (string, bool) {
+	if tag == "" {
+		return "", false
+	}
+	l := normalizeLocale(tag)
+	if _, ok := y.catalogs[l]; ok {
+		return l, true
+	}
+	base := baseLanguage(l)
+	if _, ok := y.catalogs[base]; ok {
+		return base, true
+	}
+	return "", false
+}
+
+// T translates key for locale, args are key value pairs or a single map filling {name}
+// placeholders, the arg count selects the plural form. The key itself is used when
+// no catalog has it
+func (y *YagoI18n) T(locale, key string, args ...interface{}) string {
+	return y.translate(locale, key, key, args...)
+}
+
+func (y *YagoI18n) translate(locale, key, def string, args ...interface{}) string {
+
+	params := i18nArgs(args)
+
+	msg, msgLocale := y.lookup(locale, key)
+	if msg == nil {
+		return interpolate(def, params)
+	}
+
+	form := "other"
+	if n, ok := params["count"]; ok {
+		if f, err := toFloat(n); err == nil {
+			form = pluralCategory(msgLocale, int64(f))
+		}
+	}
+	text, ok := msg.forms[form]
+	if !ok {
+		text = msg.forms["other"]
+	}
+	return interpolate(text, params)
+}
+
+// lookup returns the message of key and the locale of the catalog it is found in
+func (y *YagoI18n) lookup(locale, key string) (*yagoI18nMessage, string) {
+	if y == nil {
+		return nil, ""
+	}
+	locale = normalizeLocale(locale)
+	for _, l := range []string{locale, baseLanguage(locale), y.defaultLocale()} {
+		if msg, ok := y.catalogs[l][key]; ok {
+			return msg, l
+		}
+	}
+	return nil, ""
+}
+
+func i18nArgs(args []interface{}) map[string]interface{} {
+	if len(args) == 1 {
+		switch m := args[0].(type) {
+		case map[string]interface{}:
+			return m
+		case map[string]string:
+			r := make(map[string]interface{}, len(m))
+			for k, v := range m {
+				r[k] = v
+			}
+			return r
+		}
+	}
+	r := make(map[string]interface{}, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		if k, ok := args[i].(string); ok {
+			r[k] = args[i+1]
+		}
+	}
+	return r
+}
+
+func interpolate(text string, params map[string]interface{}) string {
+	if len(params) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for k, v := range params {
+		pairs = append(pairs, "{"+k+"}", fmt.Sprint(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func parseJSONCatalog(bs []byte) (map[string]*yagoI18nMessage, error) {
+
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(bs, &raw); err != nil {
+		return nil, err
+	}
+
+	catalog := make(map[string]*yagoI18nMessage, len(raw))
+	for k, v := range raw {
+		var text string
+		if err := json.Unmarshal(v, &text); err == nil {
+			catalog[k] = &yagoI18nMessage{forms: map[string]string{"other": text}}
+			continue
+		}
+		forms := make(map[string]string)
+		if err := json.Unmarshal(v, &forms); err != nil {
+			return nil, fmt.Errorf("message %s must be a string or plural forms", k)
+		}
+		catalog[k] = &yagoI18nMessage{forms: forms}
+	}
+	return catalog, nil
+}
+
+// parsePOCatalog parses a gettext po file, msgstr[n] is bound to categories[n],
+// untranslated messages are skipped so that they fall back to the default locale
+func parsePOCatalog(bs []byte, categories []string) (map[string]*yagoI18nMessage, error) {
+
+	catalog := make(map[string]*yagoI18nMessage)
+
+	// target is the keyword continuation strings are appended to, idx is
+	// the plural index of msgstr, -1 for a singular msgstr
+	var msgid, target string
+	var idx int
+	strs := make(map[int]string)
+
+	flush := func() {
+		forms := make(map[string]string)
+		for i, s := range strs {
+			if s == "" {
+				continue
+			}
+			if i < 0 {
+				forms["other"] = s
+			} else {
+				forms[categories[i]] = s
+			}
+		}
+		if msgid != "" && len(forms) > 0 {
+			catalog[msgid] = &yagoI18nMessage{forms: forms}
+		}
+		msgid, target, strs = "", "", make(map[int]string)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyword, value := "", line
+		if !strings.HasPrefix(line, `"`) {
+			i := strings.IndexByte(line, ' ')
+			if i < 0 {
+				return nil, fmt.Errorf("line %d: invalid syntax", n)
+			}
+			keyword, value = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", n, err.Error())
+		}
+
+		switch {
+		case keyword == "":
+			switch target {
+			case "":
+				return nil, fmt.Errorf("line %d: unexpected string", n)
+			case "msgid":
+				msgid += s
+			case "msgstr":
+				strs[idx] += s
+			}
+		case keyword == "msgctxt":
+			if target == "msgstr" {
+				flush()
+			}
+			target = keyword
+		case keyword == "msgid":
+			if target == "msgstr" {
+				flush()
+			}
+			msgid, target = s, keyword
+		case keyword == "msgid_plural":
+			target = keyword
+		case keyword == "msgstr":
+			idx, target = -1, keyword
+			strs[idx] = s
+		case strings.HasPrefix(keyword, "msgstr["):
+			i, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(keyword, "msgstr["), "]"))
+			if err != nil || i < 0 || i >= len(categories) {
+				return nil, fmt.Errorf("line %d: invalid plural index %s", n, keyword)
+			}
+			idx, target = i, "msgstr"
+			strs[idx] = s
+		default:
+			return nil, fmt.Errorf("line %d: unknown keyword %s", n, keyword)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return catalog, nil
+}
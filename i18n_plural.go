@@ -0,0 +1,131 @@
+package yago
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// normalizeLocale lowercases a language tag and uses - as separator, eg: zh_CN => zh-cn
+func normalizeLocale(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+func baseLanguage(locale string) string {
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		return locale[:i]
+	}
+	return locale
+}
+
+// parseAcceptLanguage returns language tags of an Accept-Language header by quality
+func parseAcceptLanguage(header string) []string {
+
+	type tagQuality struct {
+		tag string
+		q   float64
+	}
+
+	tags := []tagQuality{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tagQuality{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	r := make([]string, len(tags))
+	for i, v := range tags {
+		r[i] = v.tag
+	}
+	return r
+}
+
+// pluralCategories returns the CLDR plural categories of a locale in gettext msgstr[n] order
+func pluralCategories(locale string) []string {
+	switch baseLanguage(locale) {
+	case "zh", "ja", "ko", "vi", "th", "id", "ms":
+		return []string{"other"}
+	case "ru", "uk", "be", "pl", "sr", "hr", "bs":
+		return []string{"one", "few", "many"}
+	case "cs", "sk":
+		return []string{"one", "few", "other"}
+	case "ar":
+		return []string{"zero", "one", "two", "few", "many", "other"}
+	}
+	return []string{"one", "other"}
+}
+
+// pluralCategory returns the CLDR plural category of n for a locale
+func pluralCategory(locale string, n int64) string {
+	if n < 0 {
+		n = -n
+	}
+	mod10, mod100 := n%10, n%100
+
+	switch baseLanguage(locale) {
+	case "zh", "ja", "ko", "vi", "th", "id", "ms":
+		return "other"
+	case "fr", "pt":
+		if n <= 1 {
+			return "one"
+		}
+		return "other"
+	case "ru", "uk", "be", "sr", "hr", "bs":
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		}
+		return "many"
+	case "pl":
+		switch {
+		case n == 1:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		}
+		return "many"
+	case "cs", "sk":
+		switch {
+		case n == 1:
+			return "one"
+		case n >= 2 && n <= 4:
+			return "few"
+		}
+		return "other"
+	case "ar":
+		switch {
+		case n == 0:
+			return "zero"
+		case n == 1:
+			return "one"
+		case n == 2:
+			return "two"
+		case mod100 >= 3 && mod100 <= 10:
+			return "few"
+		case mod100 >= 11:
+			return "many"
+		}
+		return "other"
+	}
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
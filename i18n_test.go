@@ -0,0 +1,128 @@
+package yago
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testCatalogs = fstest.MapFS{
+	"i18n/en.json": {Data: []byte(`{
+		"app.name": "Office Assistant",
+		"hello": "Hello {name}",
+		"todo.count": {"one": "{count} todo", "other": "{count} todos"},
+		"yago.api.service_not_found": "no such service"
+	}`)},
+	"i18n/zh-CN.json": {Data: []byte(`{"app.name": "办公助手", "yago.api.service_not_found": "服务不存在"}`)},
+	"i18n/ru.po": {Data: []byte(`# russian
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=3;\n"
+
+msgid "hello"
+msgstr "Привет "
+"{name}"
+
+msgid "todo.count"
+msgid_plural "todo.count"
+msgstr[0] "{count} задача"
+msgstr[1] "{count} задачи"
+msgstr[2] "{count} задач"
+
+msgid "untranslated"
+msgstr ""
+`)},
+}
+
+func newTestI18n(t *testing.T) *YagoI18n {
+	i18n, err := NewYagoI18n(&YagoI18nConfig{FS: testCatalogs, Dir: "i18n"})
+	assert.Nil(t, err)
+	return i18n
+}
+
+func TestYagoI18nT(t *testing.T) {
+
+	i18n := newTestI18n(t)
+	assert.Equal(t, []string{"en", "ru", "zh-cn"}, i18n.Locales())
+
+	var uts = []struct {
+		Locale string
+		Key    string
+		Args   []interface{}
+		Expect string
+	}{
+		{Locale: "zh-CN", Key: "app.name", Expect: "办公助手"},
+		{Locale: "zh-cn", Key: "hello", Args: []interface{}{"name", "yago"}, Expect: "Hello yago"},
+		{Locale: "en", Key: "todo.count", Args: []interface{}{"count", 1}, Expect: "1 todo"},
+		{Locale: "en", Key: "todo.count", Args: []interface{}{map[string]interface{}{"count": 5}}, Expect: "5 todos"},
+		{Locale: "ru", Key: "hello", Args: []interface{}{"name", "yago"}, Expect: "Привет yago"},
+		{Locale: "ru", Key: "todo.count", Args: []interface{}{"count", 21}, Expect: "21 задача"},
+		{Locale: "ru", Key: "todo.count", Args: []interface{}{"count", 3}, Expect: "3 задачи"},
+		{Locale: "ru", Key: "todo.count", Args: []interface{}{"count", 11}, Expect: "11 задач"},
+		{Locale: "ru", Key: "untranslated", Expect: "untranslated"},
+		{Locale: "ru-RU", Key: "app.name", Expect: "Office Assistant"},
+	}
+	for _, uc := range uts {
+		assert.Equal(t, uc.Expect, i18n.T(uc.Locale, uc.Key, uc.Args...), uc.Locale+" "+uc.Key)
+	}
+}
+
+func TestYagoI18nDetect(t *testing.T) {
+
+	i18n := newTestI18n(t)
+
+	var uts = []struct {
+		Query  string
+		Cookie string
+		Accept string
+		Expect string
+	}{
+		{Expect: "en"},
+		{Accept: "fr;q=0.9, ru-RU;q=0.8, zh-CN", Expect: "zh-cn"},
+		{Accept: "fr, ru-RU;q=0.8", Expect: "ru"},
+		{Cookie: "ru", Accept: "zh-CN", Expect: "ru"},
+		{Query: "zh_CN", Cookie: "ru", Expect: "zh-cn"},
+		{Query: "de", Expect: "en"},
+	}
+	for _, uc := range uts {
+		r := httptest.NewRequest(http.MethodGet, "/?lang="+uc.Query, nil)
+		if uc.Cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "lang", Value: uc.Cookie})
+		}
+		r.Header.Set("Accept-Language", uc.Accept)
+		assert.Equal(t, uc.Expect, i18n.Detect(r), uc)
+	}
+}
+
+func TestYagoI18nServers(t *testing.T) {
+
+	tServer := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/p",
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "page", Templates: []string{"page.layout"}}},
+	}, fstest.MapFS{"page.layout": {Data: []byte(`{{T "app.name"}}|{{T "todo.count" "count" .}}`)}})
+	assert.Nil(t, tServer.Register("page", func(ctx *YagoContext) (interface{}, error) { return 2, nil }))
+
+	aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/a/", Timeout: 1000})
+
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithI18n(newTestI18n(t)), WithTemplateServer(tServer), WithApiServer(aServer))
+	assert.Nil(t, err)
+
+	for accept, expect := range map[string]string{"zh-CN": "办公助手|2 todos", "ru": "Office Assistant|2 задачи"} {
+		r := httptest.NewRequest(http.MethodGet, "/p/page", nil)
+		r.Header.Set("Accept-Language", accept)
+		w := httptest.NewRecorder()
+		y.ServeHTTP(w, r)
+		assert.Equal(t, expect, w.Body.String())
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/a/none?lang=zh-CN", nil)
+	w := httptest.NewRecorder()
+	y.ServeHTTP(w, r)
+	rsp := &YagoAPIWrapper{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), rsp))
+	assert.Equal(t, "服务不存在", rsp.Msg)
+}
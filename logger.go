@@ -1,18 +1,153 @@
 package yago
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
 
+// LogLevel orders log records, the values match log/slog levels
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = -4
+	LogLevelInfo  LogLevel = 0
+	LogLevelWarn  LogLevel = 4
+	LogLevelError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+	switch {
+	case l <= LogLevelDebug:
+		return "DEBUG"
+	case l <= LogLevelInfo:
+		return "INFO"
+	case l <= LogLevelWarn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// Logger is a leveled structured logger, kvs are key value pairs of fields,
+// eg: logger.Info("[Yago] Server Startup", "port", 8080)
 type Logger interface {
-	Log(msg ...interface{})
-	Loglnf(format string, args ...interface{})
+	Debug(msg string, kvs ...interface{})
+	Info(msg string, kvs ...interface{})
+	Warn(msg string, kvs ...interface{})
+	Error(msg string, kvs ...interface{})
+
+	// With returns a logger adding kvs to every record
+	With(kvs ...interface{}) Logger
+}
+
+// defaultLoggerMu serializes records of every DefaultLogger writing to the same output
+var defaultLoggerMu sync.Mutex
+
+// DefaultLogger writes text records to Out, default stdout, eg:
+// 2024-01-02T15:04:05.000Z07:00 INFO [Yago] Server Startup port=8080
+type DefaultLogger struct {
+	// Level is the lowest level written, default LogLevelInfo, per request records are debug
+	Level LogLevel
+
+	Out io.Writer
+
+	fields []interface{}
+}
+
+func (d *DefaultLogger) Debug(msg string, kvs ...interface{}) {
+	d.log(LogLevelDebug, msg, kvs)
+}
+
+func (d *DefaultLogger) Info(msg string, kvs ...interface{}) {
+	d.log(LogLevelInfo, msg, kvs)
+}
+
+func (d *DefaultLogger) Warn(msg string, kvs ...interface{}) {
+	d.log(LogLevelWarn, msg, kvs)
 }
 
-type DefaultLogger struct{}
+func (d *DefaultLogger) Error(msg string, kvs ...interface{}) {
+	d.log(LogLevelError, msg, kvs)
+}
 
-func (d *DefaultLogger) Log(msgs ...interface{}) {
-	fmt.Println(msgs...)
+func (d *DefaultLogger) With(kvs ...interface{}) Logger {
+	fields := make([]interface{}, 0, len(d.fields)+len(kvs))
+	fields = append(append(fields, d.fields...), kvs...)
+	return &DefaultLogger{Level: d.Level, Out: d.Out, fields: fields}
 }
 
-func (d *DefaultLogger) Loglnf(format string, args ...interface{}) {
-	fmt.Printf(format+"\n", args...)
+func (d *DefaultLogger) log(level LogLevel, msg string, kvs []interface{}) {
+
+	if level < d.Level {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(time.Now().Format("2006-01-02T15:04:05.000Z07:00"))
+	buf.WriteByte(' ')
+	buf.WriteString(level.String())
+	buf.WriteByte(' ')
+	buf.WriteString(msg)
+	writeLogFields(buf, d.fields)
+	writeLogFields(buf, kvs)
+	buf.WriteByte('\n')
+
+	out := d.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	defaultLoggerMu.Lock()
+	defer defaultLoggerMu.Unlock()
+	out.Write(buf.Bytes())
 }
+
+// writeLogFields writes kvs as key=value, a value without key is written with the key !BADKEY
+func writeLogFields(buf *bytes.Buffer, kvs []interface{}) {
+	for i := 0; i < len(kvs); i += 2 {
+		key, ok := kvs[i].(string)
+		val := interface{}(nil)
+		if !ok || i+1 == len(kvs) {
+			key, val = "!BADKEY", kvs[i]
+			i--
+		} else {
+			val = kvs[i+1]
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(logValue(val))
+	}
+}
+
+func logValue(v interface{}) string {
+	var s string
+	switch t := v.(type) {
+	case string:
+		s = t
+	case error:
+		s = t.Error()
+	case fmt.Stringer:
+		s = t.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// nopLogger drops every record
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, kvs ...interface{}) {}
+func (nopLogger) Info(msg string, kvs ...interface{})  {}
+func (nopLogger) Warn(msg string, kvs ...interface{})  {}
+func (nopLogger) Error(msg string, kvs ...interface{}) {}
+func (n nopLogger) With(kvs ...interface{}) Logger     { return n }
@@ -0,0 +1,40 @@
+//go:build go1.21
+
+package yago
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger adapts a *slog.Logger to Logger
+type SlogLogger struct {
+	l *slog.Logger
+}
+
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &SlogLogger{l: l}
+}
+
+func (s *SlogLogger) Debug(msg string, kvs ...interface{}) {
+	s.l.Log(context.Background(), slog.LevelDebug, msg, kvs...)
+}
+
+func (s *SlogLogger) Info(msg string, kvs ...interface{}) {
+	s.l.Log(context.Background(), slog.LevelInfo, msg, kvs...)
+}
+
+func (s *SlogLogger) Warn(msg string, kvs ...interface{}) {
+	s.l.Log(context.Background(), slog.LevelWarn, msg, kvs...)
+}
+
+func (s *SlogLogger) Error(msg string, kvs ...interface{}) {
+	s.l.Log(context.Background(), slog.LevelError, msg, kvs...)
+}
+
+func (s *SlogLogger) With(kvs ...interface{}) Logger {
+	return &SlogLogger{l: s.l.With(kvs...)}
+}
@@ -0,0 +1,23 @@
+//go:build go1.21
+
+package yago
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger.Debug("[Yago] debug", "k", "v")
+	logger.With("requestId", "r1").Warn("[Yago] warn", "k", "v")
+
+	assert.NotContains(t, buf.String(), "debug")
+	assert.Contains(t, buf.String(), `level=WARN msg="[Yago] warn" requestId=r1 k=v`)
+}
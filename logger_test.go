@@ -0,0 +1,86 @@
+package yago
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultLogger(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	logger := &DefaultLogger{Level: LogLevelInfo, Out: buf}
+
+	logger.Debug("[Yago] debug")
+	logger.With("requestId", "r1").Warn("[Yago] warn", "path", "/a b", "err", errors.New("fail"), "odd")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 1, len(lines))
+	assert.Regexp(t, `^\S+ WARN \[Yago\] warn requestId=r1 path="/a b" err=fail !BADKEY=odd$`, lines[0])
+}
+
+// testLogger records messages with their fields
+type testLogger struct {
+	mu      *sync.Mutex
+	fields  []interface{}
+	records *[]string
+}
+
+func newTestLogger() *testLogger {
+	return &testLogger{mu: &sync.Mutex{}, records: &[]string{}}
+}
+
+func (l *testLogger) record(level, msg string, kvs []interface{}) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(level + " " + msg)
+	writeLogFields(buf, append(append([]interface{}{}, l.fields...), kvs...))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.records = append(*l.records, buf.String())
+}
+
+func (l *testLogger) Debug(msg string, kvs ...interface{}) { l.record("DEBUG", msg, kvs) }
+func (l *testLogger) Info(msg string, kvs ...interface{})  { l.record("INFO", msg, kvs) }
+func (l *testLogger) Warn(msg string, kvs ...interface{})  { l.record("WARN", msg, kvs) }
+func (l *testLogger) Error(msg string, kvs ...interface{}) { l.record("ERROR", msg, kvs) }
+
+func (l *testLogger) With(kvs ...interface{}) Logger {
+	return &testLogger{mu: l.mu, fields: append(append([]interface{}{}, l.fields...), kvs...), records: l.records}
+}
+
+func (l *testLogger) lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, *l.records...)
+}
+
+func TestYagoContextLogger(t *testing.T) {
+
+	aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/a/", Timeout: 1000})
+	assert.Nil(t, aServer.Register("apidemo", func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) {
+		ctx.Logger().Info("[Demo] handle", "field", in.Field)
+		return &DemoRsp{}, nil
+	}))
+
+	logger := newTestLogger()
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithLogger(logger), WithApiServer(aServer))
+	assert.Nil(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/a/apidemo", strings.NewReader(`{"Field":"yago"}`))
+	r.RemoteAddr = "10.0.0.1:1234"
+	y.ServeHTTP(httptest.NewRecorder(), r)
+
+	var handled string
+	for _, line := range logger.lines() {
+		if strings.HasPrefix(line, "INFO [Demo]") {
+			handled = line
+		}
+	}
+	assert.Regexp(t, `^INFO \[Demo\] handle requestId=[0-9a-f]{16} route=/a/ service=apidemo remoteAddr=10.0.0.1:1234 field=yago$`, handled)
+}
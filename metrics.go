@@ -0,0 +1,308 @@
+package yago
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMetricsRoute     = "/metrics"
+	defaultMetricsMaxSeries = 1000
+
+	// metricsOverflow replaces every label of series beyond MaxSeries
+	metricsOverflow = "other"
+
+	// metricsNoHandler labels requests no handler serves
+	metricsNoHandler = "none"
+)
+
+var defaultMetricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// YagoMetricsConfig
+// YagoMetricsConfig exposes request and runtime metrics in Prometheus text format.
+// Requests are labeled by handler type, service name and code, which is the
+// YagoAPIWrapper.Code of api requests and the http status of others, paths are never labels
+type YagoMetricsConfig struct {
+	// Route is the path of the metrics endpoint, default /metrics
+	Route string `json:"route"`
+
+	// Buckets are the upper bounds in seconds of the latency histogram, default 5ms to 10s
+	Buckets []float64 `json:"buckets"`
+
+	// MaxSeries bounds the label sets of each metric, requests beyond it are
+	// counted with every label set to other, default 1000
+	MaxSeries int `json:"maxSeries"`
+}
+
+type YagoMetrics struct {
+	c       *YagoMetricsConfig
+	buckets []float64
+
+	mu        sync.Mutex
+	requests  map[string]*yagoMetricCounter
+	errors    map[string]*yagoMetricCounter
+	durations map[string]*yagoMetricHistogram
+	inFlight  map[string]int64
+}
+
+type yagoMetricCounter struct {
+	labels []string
+	value  uint64
+}
+
+type yagoMetricHistogram struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func NewYagoMetrics(c *YagoMetricsConfig) (*YagoMetrics, error) {
+	if c == nil {
+		return nil, errors.New("empty metrics config is not allowed")
+	}
+	if c.Route == "" {
+		c.Route = defaultMetricsRoute
+	}
+	buckets := append([]float64{}, c.Buckets...)
+	if len(buckets) == 0 {
+		buckets = defaultMetricsBuckets
+	}
+	sort.Float64s(buckets)
+	return &YagoMetrics{
+		c:         c,
+		buckets:   buckets,
+		requests:  make(map[string]*yagoMetricCounter),
+		errors:    make(map[string]*yagoMetricCounter),
+		durations: make(map[string]*yagoMetricHistogram),
+		inFlight:  make(map[string]int64),
+	}, nil
+}
+
+func (y *YagoMetrics) Handler() http.Handler {
+	return y
+}
+
+func (y *YagoMetrics) Pattern() string {
+	return y.c.Route
+}
+
+func (y *YagoMetrics) Type() string {
+	return "YagoMetrics"
+}
+
+func (y *YagoMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != y.c.Route {
+		http.NotFound(w, r)
+		return
+	}
+	buf := &bytes.Buffer{}
+	y.write(buf)
+	writeRuntimeMetrics(buf)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// begin counts a request of handler in flight until end
+func (y *YagoMetrics) begin(handler string) {
+	if handler == "" {
+		handler = metricsNoHandler
+	}
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.inFlight[handler]++
+}
+
+func (y *YagoMetrics) end(handler string, info *yagoRequestInfo, status int, latency time.Duration) {
+
+	if handler == "" {
+		handler = metricsNoHandler
+	}
+	code := strconv.Itoa(status)
+	failed := status >= http.StatusInternalServerError
+	if info.hasAPICode {
+		code = strconv.Itoa(info.apiCode)
+		failed = failed || info.apiCode != CodeYagoAPISucc
+	}
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	y.inFlight[handler]--
+
+	y.counter(y.requests, handler, info.service, code).value++
+	if failed {
+		y.counter(y.errors, handler, info.service, code).value++
+	}
+
+	h := y.histogram(handler, info.service)
+	seconds := latency.Seconds()
+	for i, b := range y.buckets {
+		if seconds <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+func (y *YagoMetrics) counter(m map[string]*yagoMetricCounter, labels ...string) *yagoMetricCounter {
+	labels = y.bound(len(m), m[metricsKey(labels)] != nil, labels)
+	key := metricsKey(labels)
+	c, ok := m[key]
+	if !ok {
+		c = &yagoMetricCounter{labels: labels}
+		m[key] = c
+	}
+	return c
+}
+
+func (y *YagoMetrics) histogram(labels ...string) *yagoMetricHistogram {
+	labels = y.bound(len(y.durations), y.durations[metricsKey(labels)] != nil, labels)
+	key := metricsKey(labels)
+	h, ok := y.durations[key]
+	if !ok {
+		h = &yagoMetricHistogram{labels: labels, counts: make([]uint64, len(y.buckets))}
+		y.durations[key] = h
+	}
+	return h
+}
+
+// bound replaces labels of a new series with other once a metric has MaxSeries series
+func (y *YagoMetrics) bound(series int, exists bool, labels []string) []string {
+	maxSeries := y.c.MaxSeries
+	if maxSeries <= 0 {
+		maxSeries = defaultMetricsMaxSeries
+	}
+	if exists || series < maxSeries {
+		return labels
+	}
+	overflow := make([]string, len(labels))
+	for i := range overflow {
+		overflow[i] = metricsOverflow
+	}
+	return overflow
+}
+
+func (y *YagoMetrics) write(buf *bytes.Buffer) {
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	buf.WriteString("# HELP yago_http_requests_total Count of requests by handler, service and code.\n")
+	buf.WriteString("# TYPE yago_http_requests_total counter\n")
+	for _, key := range sortedMetricKeys(y.requests) {
+		c := y.requests[key]
+		fmt.Fprintf(buf, "yago_http_requests_total%s %d\n", metricLabels(requestLabelNames, c.labels), c.value)
+	}
+
+	buf.WriteString("# HELP yago_http_request_errors_total Count of requests failed with a 5xx status or a non zero api code.\n")
+	buf.WriteString("# TYPE yago_http_request_errors_total counter\n")
+	for _, key := range sortedMetricKeys(y.errors) {
+		c := y.errors[key]
+		fmt.Fprintf(buf, "yago_http_request_errors_total%s %d\n", metricLabels(requestLabelNames, c.labels), c.value)
+	}
+
+	buf.WriteString("# HELP yago_http_request_duration_seconds Latency of requests by handler and service.\n")
+	buf.WriteString("# TYPE yago_http_request_duration_seconds histogram\n")
+	keys := make([]string, 0, len(y.durations))
+	for key := range y.durations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		h := y.durations[key]
+		for i, b := range y.buckets {
+			labels := metricLabels(bucketLabelNames, append(append([]string{}, h.labels...), formatMetricFloat(b)))
+			fmt.Fprintf(buf, "yago_http_request_duration_seconds_bucket%s %d\n", labels, h.counts[i])
+		}
+		labels := metricLabels(bucketLabelNames, append(append([]string{}, h.labels...), "+Inf"))
+		fmt.Fprintf(buf, "yago_http_request_duration_seconds_bucket%s %d\n", labels, h.count)
+		fmt.Fprintf(buf, "yago_http_request_duration_seconds_sum%s %s\n", metricLabels(durationLabelNames, h.labels), formatMetricFloat(h.sum))
+		fmt.Fprintf(buf, "yago_http_request_duration_seconds_count%s %d\n", metricLabels(durationLabelNames, h.labels), h.count)
+	}
+
+	buf.WriteString("# HELP yago_http_requests_in_flight Count of requests being served by handler.\n")
+	buf.WriteString("# TYPE yago_http_requests_in_flight gauge\n")
+	handlers := make([]string, 0, len(y.inFlight))
+	for handler := range y.inFlight {
+		handlers = append(handlers, handler)
+	}
+	sort.Strings(handlers)
+	for _, handler := range handlers {
+		fmt.Fprintf(buf, "yago_http_requests_in_flight%s %d\n", metricLabels([]string{"handler"}, []string{handler}), y.inFlight[handler])
+	}
+}
+
+var (
+	requestLabelNames  = []string{"handler", "service", "code"}
+	durationLabelNames = []string{"handler", "service"}
+	bucketLabelNames   = []string{"handler", "service", "le"}
+)
+
+func writeRuntimeMetrics(buf *bytes.Buffer) {
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	gauges := []struct {
+		name, help, kind string
+		value            string
+	}{
+		{"go_goroutines", "Number of goroutines that currently exist.", "gauge", strconv.Itoa(runtime.NumGoroutine())},
+		{"go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", "gauge", strconv.FormatUint(ms.Alloc, 10)},
+		{"go_memstats_sys_bytes", "Number of bytes obtained from system.", "gauge", strconv.FormatUint(ms.Sys, 10)},
+		{"go_memstats_heap_objects", "Number of allocated objects.", "gauge", strconv.FormatUint(ms.HeapObjects, 10)},
+		{"go_memstats_mallocs_total", "Total number of mallocs.", "counter", strconv.FormatUint(ms.Mallocs, 10)},
+		{"go_gc_cycles_total", "Number of completed GC cycles.", "counter", strconv.FormatUint(uint64(ms.NumGC), 10)},
+		{"go_gc_pause_seconds_total", "Total GC pause time in seconds.", "counter", formatMetricFloat(float64(ms.PauseTotalNs) / 1e9)},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", g.name, g.help, g.name, g.kind, g.name, g.value)
+	}
+	fmt.Fprintf(buf, "# HELP go_info Information about the Go environment.\n# TYPE go_info gauge\ngo_info%s 1\n",
+		metricLabels([]string{"version"}, []string{runtime.Version()}))
+}
+
+func metricsKey(labels []string) string {
+	return strings.Join(labels, "\xff")
+}
+
+func sortedMetricKeys(m map[string]*yagoMetricCounter) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func metricLabels(names, values []string) string {
+	buf := &strings.Builder{}
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(name)
+		buf.WriteString(`="`)
+		buf.WriteString(metricLabelEscaper.Replace(values[i]))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatMetricFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,57 @@
+package yago
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoMetrics(t *testing.T) {
+
+	aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/a/", Timeout: 1000})
+	assert.Nil(t, aServer.Register("apidemo", func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) { return &DemoRsp{}, nil }))
+
+	tServer := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/p",
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "item", Path: "/item/{id}", Templates: []string{"page.layout"}}},
+	}, fstest.MapFS{"page.layout": {Data: []byte(`item`)}})
+	assert.Nil(t, tServer.Register("item", func(ctx *YagoContext) (interface{}, error) { return nil, nil }))
+
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithMetrics(&YagoMetricsConfig{MaxSeries: 4, Buckets: []float64{1}}),
+		WithApiServer(aServer), WithTemplateServer(tServer))
+	assert.Nil(t, err)
+
+	for _, p := range []string{"/a/apidemo", "/a/apidemo", "/a/missing/1", "/a/missing/2", "/p/item/1", "/p/item/2", "/none/1"} {
+		y.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, p, strings.NewReader(`{}`)))
+	}
+	y.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/p/item/3", nil))
+
+	w := httptest.NewRecorder()
+	y.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+
+	for _, expect := range []string{
+		`yago_http_requests_total{handler="YagoApiServer",service="apidemo",code="0"} 2`,
+		`yago_http_requests_total{handler="YagoApiServer",service="",code="-100001"} 2`,
+		`yago_http_requests_total{handler="YagoTemplateServer",service="item",code="404"} 2`,
+		`yago_http_requests_total{handler="none",service="",code="404"} 1`,
+		`yago_http_requests_total{handler="other",service="other",code="other"} 1`,
+		`yago_http_request_errors_total{handler="YagoApiServer",service="",code="-100001"} 2`,
+		`yago_http_request_duration_seconds_bucket{handler="YagoApiServer",service="apidemo",le="1"} 2`,
+		`yago_http_request_duration_seconds_bucket{handler="YagoApiServer",service="apidemo",le="+Inf"} 2`,
+		`yago_http_request_duration_seconds_count{handler="YagoTemplateServer",service="item"} 3`,
+		`yago_http_requests_in_flight{handler="YagoMetrics"} 1`,
+		`yago_http_requests_in_flight{handler="YagoApiServer"} 0`,
+		"# TYPE go_goroutines gauge",
+		"go_info{version=",
+	} {
+		assert.Contains(t, body, expect)
+	}
+	// raw paths are never labels
+	assert.NotContains(t, body, "missing")
+}
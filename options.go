@@ -1,5 +1,7 @@
 package yago
 
+import "errors"
+
 type Option func(*Yago) error
 
 func WithConfig(yc *YagoConfig) Option {
@@ -11,6 +13,9 @@ func WithConfig(yc *YagoConfig) Option {
 
 func WithFileServer(fsServer *YagoFileServer) Option {
 	return func(y *Yago) error {
+		if fsServer == nil {
+			return errors.New("nil file server is not allowed")
+		}
 		y.handlers = append(y.handlers, fsServer)
 		y.paths[fsServer.Pattern()] = fsServer
 		return nil
@@ -19,6 +24,9 @@ func WithFileServer(fsServer *YagoFileServer) Option {
 
 func WithTemplateServer(tServer *YagoTemplateServer) Option {
 	return func(y *Yago) error {
+		if tServer == nil {
+			return errors.New("nil template server is not allowed")
+		}
 		y.handlers = append(y.handlers, tServer)
 		y.paths[tServer.Pattern()] = tServer
 		return nil
@@ -27,8 +35,101 @@ func WithTemplateServer(tServer *YagoTemplateServer) Option {
 
 func WithApiServer(aServer *YagoApiServer) Option {
 	return func(y *Yago) error {
+		if aServer == nil {
+			return errors.New("nil api server is not allowed")
+		}
 		y.handlers = append(y.handlers, aServer)
 		y.paths[aServer.Pattern()] = aServer
 		return nil
 	}
 }
+
+func WithWsServer(wServer *YagoWsServer) Option {
+	return func(y *Yago) error {
+		if wServer == nil {
+			return errors.New("nil ws server is not allowed")
+		}
+		y.handlers = append(y.handlers, wServer)
+		y.paths[wServer.Pattern()] = wServer
+		return nil
+	}
+}
+
+// WithLogger replaces the DefaultLogger of Yago and every server mounted on it
+func WithLogger(logger Logger) Option {
+	return func(y *Yago) error {
+		if logger == nil {
+			return errors.New("nil logger is not allowed")
+		}
+		y.logger = logger
+		return nil
+	}
+}
+
+// WithAccessLog writes a record for every request, see YagoAccessLogConfig
+func WithAccessLog(c *YagoAccessLogConfig) Option {
+	return func(y *Yago) error {
+		accessLog, err := NewYagoAccessLog(c)
+		if err != nil {
+			return err
+		}
+		y.accessLog = accessLog
+		return nil
+	}
+}
+
+// WithMetrics serves request and runtime metrics in Prometheus text format, see YagoMetricsConfig
+func WithMetrics(c *YagoMetricsConfig) Option {
+	return func(y *Yago) error {
+		metrics, err := NewYagoMetrics(c)
+		if err != nil {
+			return err
+		}
+		y.metrics = metrics
+		y.handlers = append(y.handlers, metrics)
+		return nil
+	}
+}
+
+// WithTracing traces requests and exports their spans, see YagoTraceConfig
+func WithTracing(c *YagoTraceConfig) Option {
+	return func(y *Yago) error {
+		tracer, err := NewYagoTracer(c)
+		if err != nil {
+			return err
+		}
+		y.tracer = tracer
+		return nil
+	}
+}
+
+// WithHealth serves the liveness and readiness probes of health, see YagoHealthConfig
+func WithHealth(health *YagoHealth) Option {
+	return func(y *Yago) error {
+		if health == nil {
+			return errors.New("nil health is not allowed")
+		}
+		y.health = health
+		y.handlers = append(y.handlers, health.handlers()...)
+		return nil
+	}
+}
+
+// WithPanicHook calls hook with every panic recovered while serving a request, eg: to alert
+func WithPanicHook(hook YagoPanicHook) Option {
+	return func(y *Yago) error {
+		if hook == nil {
+			return errors.New("nil panic hook is not allowed")
+		}
+		y.panicHook = hook
+		return nil
+	}
+}
+
+// WithI18n translates template pages and api messages by the locale of requests
+func WithI18n(i18n *YagoI18n) Option {
+	return func(y *Yago) error {
+		y.i18n = i18n
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package yago
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// YagoPanicHook is called with every panic recovered while serving a request, eg: to alert,
+// it must not panic itself
+type YagoPanicHook func(r *http.Request, requestID string, err *YagoPanicError)
+
+// YagoPanicError is a panic recovered from a handler or a render
+type YagoPanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *YagoPanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value when it is an error
+func (e *YagoPanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic turns the result of recover into an error with the stack of the panic,
+// it is called by deferred funcs, eg: defer func() { err = recoverPanic(recover(), err) }()
+func recoverPanic(v interface{}, err error) error {
+	if v == nil {
+		return err
+	}
+	if v == http.ErrAbortHandler {
+		// net/http aborts the response silently on ErrAbortHandler
+		panic(v)
+	}
+	return &YagoPanicError{Value: v, Stack: debug.Stack()}
+}
+
+// reportPanic logs a recovered panic with its stack to the logger of the request and calls
+// the panic hook of Yago, servers serving requests without Yago only log it
+func reportPanic(yago *Yago, logger Logger, r *http.Request, requestID string, err *YagoPanicError) {
+	logger.Error("[Yago] panic recovered", "path", r.URL.Path, "panic", err.Value, "stack", string(err.Stack))
+	if yago != nil && yago.panicHook != nil {
+		yago.panicHook(r, requestID, err)
+	}
+}
+
+// reportPanicError reports err of a request when it is a recovered panic
+func reportPanicError(yago *Yago, yc *YagoContext, err error) bool {
+	var pe *YagoPanicError
+	if !errors.As(err, &pe) {
+		return false
+	}
+	reportPanic(yago, yc.Logger(), yc.r, yc.requestID, pe)
+	return true
+}
@@ -0,0 +1,116 @@
+package yago
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// panicHandler is a handler without recovery of its own, eg: a handler of another package
+type panicHandler struct {
+	v interface{}
+}
+
+func (p *panicHandler) Handler() http.Handler { return p }
+func (p *panicHandler) Pattern() string       { return "/panic" }
+func (p *panicHandler) Type() string          { return "panicHandler" }
+
+func (p *panicHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("written") != "" {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	panic(p.v)
+}
+
+func TestYagoPanicRecovery(t *testing.T) {
+
+	aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/a/", Timeout: 1000})
+	assert.Nil(t, aServer.Register("apidemo", func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) { panic("api boom") }))
+
+	tServer := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/p",
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "item", Path: "/item", Templates: []string{"page.layout"}}},
+	}, fstest.MapFS{"page.layout": {Data: []byte(`item`)}})
+	assert.Nil(t, tServer.Register("item", func(ctx *YagoContext) (interface{}, error) { panic(errors.New("page boom")) }))
+
+	var mu sync.Mutex
+	var hooked []string
+	logger := newTestLogger()
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithLogger(logger), WithApiServer(aServer), WithTemplateServer(tServer),
+		WithPanicHook(func(r *http.Request, requestID string, err *YagoPanicError) {
+			mu.Lock()
+			defer mu.Unlock()
+			hooked = append(hooked, requestID+" "+err.Error())
+		}),
+		func(y *Yago) error {
+			y.handlers = append(y.handlers, &panicHandler{v: "handler boom"})
+			return nil
+		})
+	assert.Nil(t, err)
+
+	uts := []struct {
+		path   string
+		status int
+		body   string
+		hooked string
+	}{
+		{path: "/a/apidemo", status: http.StatusOK, body: `"code":-100002,"msg":"invoke error"`, hooked: "req-1 panic: api boom"},
+		{path: "/p/item", status: http.StatusInternalServerError, body: "Internal Server Error", hooked: "req-2 panic: page boom"},
+		{path: "/panic", status: http.StatusInternalServerError, body: "Internal Server Error", hooked: "req-3 panic: handler boom"},
+	}
+
+	for i, ut := range uts {
+		r := httptest.NewRequest(http.MethodGet, ut.path, strings.NewReader(`{}`))
+		r.Header.Set(RequestIDHeader, "req-"+string(rune('1'+i)))
+		w := httptest.NewRecorder()
+		y.ServeHTTP(w, r)
+
+		assert.Equal(t, ut.status, w.Code, ut.path)
+		assert.Contains(t, w.Body.String(), ut.body, ut.path)
+		assert.Equal(t, ut.hooked, hooked[i], ut.path)
+	}
+
+	// a started response is aborted after the panic is reported
+	r := httptest.NewRequest(http.MethodGet, "/panic?written=1", nil)
+	r.Header.Set(RequestIDHeader, "req-4")
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { y.ServeHTTP(httptest.NewRecorder(), r) })
+	assert.Equal(t, "req-4 panic: handler boom", hooked[3])
+
+	var reported []string
+	for _, line := range logger.lines() {
+		if strings.Contains(line, "panic recovered") {
+			reported = append(reported, line)
+		}
+	}
+	assert.Len(t, reported, len(uts)+1)
+	assert.Contains(t, reported[0], "requestId=req-1")
+	assert.Contains(t, reported[0], "panic_test.go")
+	assert.Contains(t, reported[2], "requestId=req-3")
+
+	rsp := &YagoAPIWrapper{}
+	w := httptest.NewRecorder()
+	y.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/a/apidemo", strings.NewReader(`{}`)))
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), rsp))
+	assert.Equal(t, CodeYagoAPIInternalError, rsp.Code)
+	assert.Equal(t, w.Header().Get(RequestIDHeader), rsp.RequestID)
+}
+
+func TestRecoverPanic(t *testing.T) {
+
+	assert.Nil(t, recoverPanic(nil, nil))
+
+	cause := errors.New("boom")
+	err := recoverPanic(cause, nil)
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, "panic: boom", err.Error())
+
+	// http.ErrAbortHandler keeps aborting the response
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { recoverPanic(http.ErrAbortHandler, nil) })
+}
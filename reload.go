@@ -0,0 +1,252 @@
+package yago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"syscall"
+)
+
+// Reload reads the config file of a Yago built by NewFromConfigFile again and applies it,
+// see ApplyConfig, Start calls it on SIGHUP
+func (y *Yago) Reload() error {
+	if y.configFile == "" {
+		return errors.New("no config file to reload, yago is not built by NewFromConfigFile")
+	}
+	fc, err := LoadConfigFile(y.configFile)
+	if err != nil {
+		return err
+	}
+	return y.ApplyConfig(fc)
+}
+
+// ApplyConfig applies fc to the running servers without restart: timeouts and layouts of
+// api and template servers are updated, templates are re-parsed, file and ws servers are
+// added or removed by route. Every change is prepared and checked before any of them is
+// applied, so an invalid config leaves the running servers untouched, and requests in
+// flight finish with the config they started with.
+// Api and template servers missing in fc keep running, the port and api or template
+// servers not running yet need a restart.
+func (y *Yago) ApplyConfig(fc *YagoFileConfig) error {
+
+	if fc == nil || fc.Server == nil {
+		return errors.New("empty file config is not allowed")
+	}
+	y.reloadMu.Lock()
+	defer y.reloadMu.Unlock()
+
+	fc.inheritTimeouts()
+
+	cfg := *fc.Server
+	if y.cfg != nil && cfg.Port != y.cfg.Port {
+		y.logger.Warn("[Yago] Reload keeps port, a new port needs a restart", "port", y.cfg.Port, "newPort", cfg.Port)
+		cfg.Port = y.cfg.Port
+	}
+
+	var errs []error
+	var commits []func()
+	var handlers []YagoHandler
+
+	apis := make(map[string]*YagoApiServerConfig, len(fc.Apis))
+	for _, c := range fc.Apis {
+		apis[c.Route] = c
+	}
+	tmpls := make(map[string]*YagoTemplateConfig, len(fc.Templates))
+	for _, c := range fc.Templates {
+		tmpls[c.Route] = c
+	}
+	files := make(map[string]*YagoFileServer)
+	wss := make(map[string]*YagoWsServer)
+
+	for _, h := range y.handlerList() {
+		switch s := h.(type) {
+		case *YagoApiServer:
+			if c, ok := apis[s.Pattern()]; ok {
+				commits = append(commits, s.prepareConfig(c))
+				delete(apis, s.Pattern())
+			}
+		case *YagoTemplateServer:
+			if c, ok := tmpls[s.Pattern()]; ok {
+				commit, err := s.prepareConfig(c)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				commits = append(commits, commit)
+				delete(tmpls, s.Pattern())
+			}
+		case *YagoFileServer:
+			files[s.fsConfig.Route] = s
+			continue
+		case *YagoWsServer:
+			wss[s.Pattern()] = s
+			continue
+		}
+		handlers = append(handlers, h)
+	}
+
+	for _, c := range fc.Apis {
+		if _, ok := apis[c.Route]; ok {
+			errs = append(errs, fmt.Errorf("api server %s is not running, adding it needs a restart", c.Route))
+		}
+	}
+	for _, c := range fc.Templates {
+		if _, ok := tmpls[c.Route]; ok {
+			errs = append(errs, fmt.Errorf("template server %s is not running, adding it needs a restart", c.Route))
+		}
+	}
+
+	var added []YagoHandler
+	for _, fileConfig := range fc.Files {
+		// inherited fields are filled into a copy, the config of the caller is untouched on failure
+		cc := *fileConfig
+		c := &cc
+		old, ok := files[c.Route]
+		if ok && c.FS == nil {
+			c.FS = old.fsConfig.FS
+		}
+		if ok && reflect.DeepEqual(old.fsConfig, c) {
+			handlers = append(handlers, old)
+			continue
+		}
+		fServer, err := NewYagoFileServer(c)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		handlers = append(handlers, fServer)
+		added = append(added, fServer)
+	}
+	for _, c := range fc.Ws {
+		if old, ok := wss[c.Route]; ok {
+			handlers = append(handlers, old)
+			continue
+		}
+		wServer, err := NewYagoWsServer(c)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		handlers = append(handlers, wServer)
+		added = append(added, wServer)
+	}
+
+	next := &Yago{cfg: &cfg, handlers: handlers, logger: y.logger}
+	if err := next.check(errs...); err != nil {
+		y.logger.Error("[Yago] Reload config fail, keep running with the current config", "err", err)
+		return err
+	}
+
+	for _, commit := range commits {
+		commit()
+	}
+	for _, h := range added {
+		if b, ok := h.(yagoBinder); ok {
+			b.bind(y)
+		}
+	}
+
+	y.mu.Lock()
+	y.cfg = &cfg
+	y.handlers = handlers
+	y.paths = make(map[string]YagoHandler)
+	y.mu.Unlock()
+
+	y.logger.Info("[Yago] Reload config succ", "handlers", len(handlers))
+	return nil
+}
+
+// watchReload reloads the config file on SIGHUP until ctx is done
+func (y *Yago) watchReload(ctx context.Context) {
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			if err := y.Reload(); err != nil {
+				y.logger.Error("[Yago] Reload on SIGHUP fail", "err", err)
+			}
+		}
+	}
+}
+
+// prepareConfig returns a func swapping c in, routes of registered services do not change
+func (y *YagoApiServer) prepareConfig(c *YagoApiServerConfig) func() {
+	return func() {
+		y.c.Store(c)
+	}
+}
+
+// prepareConfig parses the templates of every registered page with c, the returned func
+// swaps the config and the renders in at once
+func (y *YagoTemplateServer) prepareConfig(tc *YagoTemplateConfig) (func(), error) {
+
+	// inherited fields are filled into a copy, the config of the caller is untouched on failure
+	cc := *tc
+	c := &cc
+	if c.FS == nil {
+		c.FS = y.config().FS
+	}
+	layoutFS, err := subFS(c.FS, c.LayoutDir)
+	if err != nil {
+		return nil, err
+	}
+
+	next := &YagoTemplateServer{
+		bindFuncs:  y.bindFuncs,
+		logger:     y.logger,
+		yago:       y.yago,
+		hds:        make(map[string]YaogoTemplateHandler),
+		renders:    make(map[string]*YagoRender),
+		renderErrs: make(map[string]error),
+		tmpls:      make(map[string][]string),
+		router:     &yagoRouter{},
+		layoutFS:   layoutFS,
+	}
+	next.c.Store(c)
+
+	if errs := next.validate(); len(errs) > 0 {
+		return nil, &YagoCheckError{Errs: errs}
+	}
+
+	y.mu.RLock()
+	services := make([]string, 0, len(y.hds))
+	hds := make(map[string]YaogoTemplateHandler, len(y.hds))
+	for service, h := range y.hds {
+		services = append(services, service)
+		hds[service] = h
+	}
+	y.mu.RUnlock()
+	sort.Strings(services)
+
+	for _, service := range services {
+		if err := next.Register(service, hds[service]); err != nil {
+			return nil, fmt.Errorf("reload page %s of %s fail: %s", service, c.Route, err.Error())
+		}
+		// DevMode keeps parse errors for the browser, a reload must not bring them in
+		if err := next.renderErrs[service]; err != nil {
+			return nil, fmt.Errorf("reload page %s of %s fail: %s", service, c.Route, err.Error())
+		}
+	}
+
+	return func() {
+		y.mu.Lock()
+		defer y.mu.Unlock()
+		y.hds = next.hds
+		y.renders = next.renders
+		y.renderErrs = next.renderErrs
+		y.tmpls = next.tmpls
+		y.router = next.router
+		y.layoutFS = next.layoutFS
+		y.c.Store(c)
+	}, nil
+}
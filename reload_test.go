@@ -0,0 +1,141 @@
+package yago
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoReload(t *testing.T) {
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"page.layout":  `page {{.}}`,
+		"page2.layout": `page2 {{.}}`,
+		"app.js":       `app`,
+	} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	file := filepath.Join(dir, "yago.yaml")
+	writeConfig := func(timeout int, page, static string) {
+		assert.Nil(t, os.WriteFile(file, []byte(`
+server:
+  timeout: 500
+apis:
+  - route: /a/
+    timeout: `+strconv.Itoa(timeout)+`
+templates:
+  - route: /p
+    layoutDir: `+filepath.ToSlash(dir)+`
+    pageLayouts:
+      - name: page
+        templates: [`+page+`]
+files:
+  - route: `+static+`
+    dir: `+filepath.ToSlash(dir)+`
+`), 0644))
+	}
+	get := func(y *Yago, p string) (int, string) {
+		w := httptest.NewRecorder()
+		y.ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, nil))
+		return w.Code, w.Body.String()
+	}
+
+	writeConfig(300, "page.layout", "static")
+	y, err := NewFromConfigFile(file)
+	assert.Nil(t, err)
+	assert.Nil(t, y.TemplateServer("/p").Register("page", func(ctx *YagoContext) (interface{}, error) { return "yago", nil }))
+
+	_, body := get(y, "/p/page")
+	assert.Equal(t, "page yago", body)
+	code, _ := get(y, "/static/app.js")
+	assert.Equal(t, http.StatusOK, code)
+
+	writeConfig(200, "page2.layout", "assets")
+	assert.Nil(t, y.Reload())
+
+	_, body = get(y, "/p/page")
+	assert.Equal(t, "page2 yago", body)
+	assert.Equal(t, 200, y.ApiServer("/a/").config().Timeout)
+	code, _ = get(y, "/static/app.js")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = get(y, "/assets/app.js")
+	assert.Equal(t, http.StatusOK, code)
+
+	// invalid configs are rejected and the running servers are untouched
+	writeConfig(100, "missing.layout", "static")
+	assert.NotNil(t, y.Reload())
+
+	_, body = get(y, "/p/page")
+	assert.Equal(t, "page2 yago", body)
+	assert.Equal(t, 200, y.ApiServer("/a/").config().Timeout)
+	code, _ = get(y, "/assets/app.js")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestYagoApplyConfigRejected(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"page.layout":   {Data: []byte(`page`)},
+		"static/app.js": {Data: []byte(`app`)},
+	}
+	y, err := NewFromConfig(&YagoFileConfig{
+		Server:    &YagoConfig{Port: 8080},
+		Templates: []*YagoTemplateConfig{{Route: "/p", FS: fsys}},
+		Files:     []*YagoFileServerConfig{{FS: fsys, Dir: "static", Route: "static"}},
+	}, WithLogger(nopLogger{}))
+	assert.Nil(t, err)
+
+	fc := &YagoFileConfig{
+		Server: &YagoConfig{Port: 8080},
+		Templates: []*YagoTemplateConfig{{
+			Route:       "/p",
+			PageLayouts: []*PageLayoutConfig{{ServiceName: "page", Templates: []string{"missing.layout"}}},
+		}},
+		Files: []*YagoFileServerConfig{{Dir: "static", Route: "static", MaxAge: 30}},
+	}
+	assert.NotNil(t, y.ApplyConfig(fc))
+
+	// the rejected config does not inherit the FS of the running servers
+	assert.Nil(t, fc.Templates[0].FS)
+	assert.Nil(t, fc.Files[0].FS)
+}
+
+func TestYagoApplyConfigDuringShutdown(t *testing.T) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := uint32(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	y, err := NewFromConfig(&YagoFileConfig{Server: &YagoConfig{Port: port}}, WithLogger(nopLogger{}))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() {
+		started <- y.Start(ctx)
+	}()
+
+	// reloads on SIGHUP may race with Start and shutdown reading the config, see go test -race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			assert.Nil(t, y.ApplyConfig(&YagoFileConfig{Server: &YagoConfig{Port: port, ShutdownTimeout: 1000 + uint32(i)}}))
+		}
+	}()
+	cancel()
+
+	assert.Nil(t, <-started)
+	<-done
+}
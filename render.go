@@ -1,13 +1,37 @@
 package yago
 
 import (
+	"bytes"
 	"errors"
 	"html/template"
+	"io/fs"
+	"net/http"
 	"path"
+	"strconv"
+	"sync"
 )
 
+// maxPooledBufferSize keeps the buffer pool from pinning memory of rare huge pages
+const maxPooledBufferSize = 1 << 20
+
+var ErrFragmentNotFound = errors.New("fragment not found")
+
+var renderBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
 type YagoRender struct {
 	t *template.Template
+
+	// execs caches clones of t by locale, t itself is never executed so that it can be
+	// cloned at any time and each clone binds the T func to its own locale
+	execs sync.Map
+
+	// stream executes templates directly into the response without buffering,
+	// a failure midway can not be reported to the client anymore
+	stream bool
 }
 
 func NewRenderWithTemplates(tps []string, bindFuncs map[string]interface{}) (*YagoRender, error) {
@@ -24,10 +48,123 @@ func NewRenderWithTemplates(tps []string, bindFuncs map[string]interface{}) (*Ya
 	return &YagoRender{t: t}, nil
 }
 
+// NewRenderWithFS parses templates from fsys, tps are slash separated paths relative to fsys
+func NewRenderWithFS(fsys fs.FS, tps []string, bindFuncs map[string]interface{}) (*YagoRender, error) {
+
+	if len(tps) == 0 {
+		return nil, errors.New("empty template found")
+	}
+
+	t, err := template.New(path.Base(tps[0])).Funcs(bindFuncs).ParseFS(fsys, tps...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &YagoRender{t: t}, nil
+}
+
 func NewRender(t *template.Template) *YagoRender {
 	return &YagoRender{t: t}
 }
 
+// SetStream switches the render to streaming mode for very large pages
+func (y *YagoRender) SetStream(stream bool) {
+	y.stream = stream
+}
+
+// Render responds data with status 200, see RenderStatus
 func (y *YagoRender) Render(ctx *YagoContext, data interface{}) error {
-	return y.t.Execute(ctx.w, data)
+	return y.RenderStatus(ctx, http.StatusOK, data)
+}
+
+// RenderStatus executes the template into a pooled buffer and writes headers
+// and body only when execution succeeds, so nothing is sent on failure
+func (y *YagoRender) RenderStatus(ctx *YagoContext, status int, data interface{}) error {
+	return y.render(ctx, status, y.execTemplate(ctx), data)
+}
+
+// RenderFragment responds only the named template, eg: a block of the page
+func (y *YagoRender) RenderFragment(ctx *YagoContext, name string, data interface{}) error {
+	t := y.execTemplate(ctx).Lookup(name)
+	if t == nil {
+		return ErrFragmentNotFound
+	}
+	return y.render(ctx, http.StatusOK, t, data)
+}
+
+// HasFragment reports whether the named template is defined in the render
+func (y *YagoRender) HasFragment(name string) bool {
+	return y.t.Lookup(name) != nil
+}
+
+func (y *YagoRender) execTemplate(ctx *YagoContext) *template.Template {
+
+	if v, ok := y.execs.Load(ctx.locale); ok {
+		return v.(*template.Template)
+	}
+
+	t, err := y.t.Clone()
+	if err != nil {
+		// t was executed before being bound to the render, eg: by NewRender
+		return y.t
+	}
+	if ctx.i18n != nil {
+		i18n, locale := ctx.i18n, ctx.locale
+		t.Funcs(template.FuncMap{"T": func(key string, args ...interface{}) string {
+			return i18n.translate(locale, key, key, args...)
+		}})
+	}
+
+	v, _ := y.execs.LoadOrStore(ctx.locale, t)
+	return v.(*template.Template)
+}
+
+// render executes t, a panic of it is returned as a *YagoPanicError
+func (y *YagoRender) render(ctx *YagoContext, status int, t *template.Template, data interface{}) (err error) {
+
+	defer func() {
+		err = recoverPanic(recover(), err)
+	}()
+
+	if y.stream {
+		setHTMLContentType(ctx.w)
+		ctx.writeResponseStatus(status)
+		if err := t.Execute(ctx.w, data); err != nil {
+			return err
+		}
+		if f, ok := ctx.w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+
+	buf := renderBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer putRenderBuffer(buf)
+
+	if err := t.Execute(buf, data); err != nil {
+		return err
+	}
+	writeHTML(ctx, status, buf.Bytes())
+	return nil
+}
+
+func putRenderBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	renderBufferPool.Put(buf)
+}
+
+func setHTMLContentType(w http.ResponseWriter) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+}
+
+func writeHTML(ctx *YagoContext, status int, body []byte) {
+	setHTMLContentType(ctx.w)
+	ctx.w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	ctx.writeResponseStatus(status)
+	ctx.w.Write(body)
 }
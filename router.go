@@ -0,0 +1,229 @@
+package yago
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// yagoRoute is an url pattern like /todo/{id} or /docs/{path...},
+// {name} matches one path segment and {name...} matches the rest of the path
+type yagoRoute struct {
+	name     string
+	pattern  string
+	segments []string
+}
+
+func newYagoRoute(name, pattern string) (*yagoRoute, error) {
+
+	r := &yagoRoute{name: name, pattern: pattern, segments: splitPath(pattern)}
+	seen := make(map[string]bool)
+
+	for i, seg := range r.segments {
+		if !isParamSegment(seg) {
+			if strings.ContainsAny(seg, "{}") {
+				return nil, errors.New("invalid route pattern segment: " + pattern)
+			}
+			continue
+		}
+		key, rest := paramOfSegment(seg)
+		if key == "" {
+			return nil, errors.New("empty param name in route pattern: " + pattern)
+		}
+		if rest && i != len(r.segments)-1 {
+			return nil, errors.New("param with ... must be the last segment: " + pattern)
+		}
+		if seen[key] {
+			return nil, errors.New("duplicate param name in route pattern: " + pattern)
+		}
+		seen[key] = true
+	}
+	return r, nil
+}
+
+// match reports whether p matches the route and returns the path params
+func (r *yagoRoute) match(p string) (map[string]string, bool) {
+
+	segs := splitPath(p)
+	params := make(map[string]string)
+
+	for i, seg := range r.segments {
+		if !isParamSegment(seg) {
+			if i >= len(segs) || segs[i] != seg {
+				return nil, false
+			}
+			continue
+		}
+		key, rest := paramOfSegment(seg)
+		if rest {
+			if i >= len(segs) {
+				return nil, false
+			}
+			params[key] = strings.Join(segs[i:], "/")
+			return params, true
+		}
+		if i >= len(segs) {
+			return nil, false
+		}
+		params[key] = segs[i]
+	}
+
+	if len(segs) != len(r.segments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// moreSpecific reports whether r should win over o when both match,
+// static segments win over params and params win over the rest params
+func (r *yagoRoute) moreSpecific(o *yagoRoute) bool {
+	for i := 0; i < len(r.segments) && i < len(o.segments); i++ {
+		rw, ow := segmentWeight(r.segments[i]), segmentWeight(o.segments[i])
+		if rw != ow {
+			return rw > ow
+		}
+	}
+	return len(r.segments) > len(o.segments)
+}
+
+// build fills params into the pattern, params not in the pattern are appended as query string
+func (r *yagoRoute) build(params map[string]string) (string, error) {
+
+	used := make(map[string]bool)
+	segs := make([]string, 0, len(r.segments))
+	for _, seg := range r.segments {
+		if !isParamSegment(seg) {
+			segs = append(segs, seg)
+			continue
+		}
+		key, rest := paramOfSegment(seg)
+		v, ok := params[key]
+		if !ok || v == "" {
+			return "", fmt.Errorf("missing param %q for route %q", key, r.name)
+		}
+		used[key] = true
+		if !rest {
+			segs = append(segs, url.PathEscape(v))
+			continue
+		}
+		for _, part := range strings.Split(strings.Trim(v, "/"), "/") {
+			segs = append(segs, url.PathEscape(part))
+		}
+	}
+
+	p := "/" + strings.Join(segs, "/")
+	query := url.Values{}
+	for k, v := range params {
+		if !used[k] {
+			query.Set(k, v)
+		}
+	}
+	if len(query) > 0 {
+		p += "?" + query.Encode()
+	}
+	return p, nil
+}
+
+type yagoRouter struct {
+	routes []*yagoRoute
+}
+
+func (y *yagoRouter) add(name, pattern string) error {
+	r, err := newYagoRoute(name, pattern)
+	if err != nil {
+		return err
+	}
+	for _, v := range y.routes {
+		if v.pattern == r.pattern {
+			return errors.New("duplicate route pattern registed: " + pattern)
+		}
+	}
+	y.routes = append(y.routes, r)
+	return nil
+}
+
+// match returns the most specific route matching p
+func (y *yagoRouter) match(p string) (*yagoRoute, map[string]string) {
+	var best *yagoRoute
+	var bestParams map[string]string
+	for _, r := range y.routes {
+		params, ok := r.match(p)
+		if !ok {
+			continue
+		}
+		if best == nil || r.moreSpecific(best) {
+			best, bestParams = r, params
+		}
+	}
+	return best, bestParams
+}
+
+// find returns the route with the name
+func (y *yagoRouter) find(name string) *yagoRoute {
+	for _, r := range y.routes {
+		if r.name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// pairsToParams converts key value pairs as passed to templates into params
+func pairsToParams(kvs []interface{}) (map[string]string, error) {
+	if len(kvs)%2 != 0 {
+		return nil, errors.New("params must be key value pairs")
+	}
+	params := make(map[string]string, len(kvs)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		k, ok := kvs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("param key must be string, got %v", kvs[i])
+		}
+		params[k] = fmt.Sprint(kvs[i+1])
+	}
+	return params, nil
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// joinRoute joins a server route and a path below it into an absolute url path
+func joinRoute(route, p string) string {
+	r := "/" + strings.Trim(route, "/")
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return r
+	}
+	if r == "/" {
+		return r + p
+	}
+	return r + "/" + p
+}
+
+func isParamSegment(seg string) bool {
+	return strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")
+}
+
+func paramOfSegment(seg string) (key string, rest bool) {
+	key = seg[1 : len(seg)-1]
+	if strings.HasSuffix(key, "...") {
+		return strings.TrimSuffix(key, "..."), true
+	}
+	return key, false
+}
+
+func segmentWeight(seg string) int {
+	if !isParamSegment(seg) {
+		return 2
+	}
+	if _, rest := paramOfSegment(seg); rest {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,50 @@
+package yago
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoRouterMatch(t *testing.T) {
+
+	router := &yagoRouter{}
+	assert.Nil(t, router.add("index", "/"))
+	assert.Nil(t, router.add("todo", "/app/todo"))
+	assert.Nil(t, router.add("archive", "/app/todo/archive"))
+	assert.Nil(t, router.add("item", "/app/todo/{id}"))
+	assert.Nil(t, router.add("docs", "/docs/{path...}"))
+	assert.NotNil(t, router.add("dup", "/app/todo"))
+	assert.NotNil(t, router.add("bad", "/docs/{path...}/x"))
+	assert.NotNil(t, router.add("bad", "/a/{id}/{id}"))
+
+	var uts = []struct {
+		Path   string
+		Name   string
+		Params map[string]string
+	}{
+		{Path: "/", Name: "index", Params: map[string]string{}},
+		{Path: "/app/todo/", Name: "todo", Params: map[string]string{}},
+		{Path: "/app/todo/archive", Name: "archive", Params: map[string]string{}},
+		{Path: "/app/todo/42", Name: "item", Params: map[string]string{"id": "42"}},
+		{Path: "/docs/guide/intro", Name: "docs", Params: map[string]string{"path": "guide/intro"}},
+		{Path: "/docs", Name: ""},
+		{Path: "/app/todo/42/x", Name: ""},
+	}
+	for _, uc := range uts {
+		r, params := router.match(uc.Path)
+		if uc.Name == "" {
+			assert.Nil(t, r, uc.Path)
+			continue
+		}
+		assert.Equal(t, uc.Name, r.name, uc.Path)
+		assert.Equal(t, uc.Params, params, uc.Path)
+	}
+}
+
+func TestJoinRoute(t *testing.T) {
+	assert.Equal(t, "/", joinRoute("/", "/"))
+	assert.Equal(t, "/todo", joinRoute("", "todo"))
+	assert.Equal(t, "/app", joinRoute("/app/", "/"))
+	assert.Equal(t, "/app/todo/{id}", joinRoute("app", "/todo/{id}"))
+}
@@ -0,0 +1,302 @@
+package yago
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	traceParentHeader = "traceparent"
+	traceStateHeader  = "tracestate"
+
+	// traceFlagSampled is the sampled flag of traceparent
+	traceFlagSampled byte = 0x01
+)
+
+// YagoSpanKind follows the OpenTelemetry span kinds
+type YagoSpanKind int
+
+const (
+	SpanKindInternal YagoSpanKind = 1
+	SpanKindServer   YagoSpanKind = 2
+	SpanKindClient   YagoSpanKind = 3
+)
+
+// YagoTraceConfig
+// YagoTraceConfig traces requests with W3C Trace Context propagation
+type YagoTraceConfig struct {
+	// ServiceName is the service.name resource of exported spans, default yago
+	ServiceName string `json:"serviceName"`
+
+	// File is the OTLP JSON lines file spans are exported to when no Exporter is set
+	File string `json:"file"`
+
+	// Exporter receives every ended span of sampled traces, eg: YagoMemoryExporter
+	Exporter YagoSpanExporter `json:"-"`
+}
+
+// YagoSpanExporter exports ended spans, the accessors of YagoSpan give what an exporter
+// needs, eg: TraceID, ParentSpanID, StartTime and Attributes
+type YagoSpanExporter interface {
+	Export(span *YagoSpan) error
+}
+
+type YagoTracer struct {
+	c        *YagoTraceConfig
+	exporter YagoSpanExporter
+	logger   Logger
+}
+
+func NewYagoTracer(c *YagoTraceConfig) (*YagoTracer, error) {
+	if c == nil {
+		return nil, errors.New("empty trace config is not allowed")
+	}
+	if c.ServiceName == "" {
+		c.ServiceName = "yago"
+	}
+	exporter := c.Exporter
+	if exporter == nil {
+		if c.File == "" {
+			return nil, errors.New("trace config needs an Exporter or a File")
+		}
+		fileExporter, err := NewYagoOTLPFileExporter(c.File, c.ServiceName)
+		if err != nil {
+			return nil, err
+		}
+		exporter = fileExporter
+	}
+	return &YagoTracer{c: c, exporter: exporter, logger: &DefaultLogger{}}, nil
+}
+
+// Close closes the exporter when it is a file
+func (t *YagoTracer) Close() error {
+	if f, ok := t.exporter.(*YagoOTLPFileExporter); ok {
+		return f.Close()
+	}
+	return nil
+}
+
+// startRequest starts the server span of r, continuing the trace of its traceparent header
+func (t *YagoTracer) startRequest(r *http.Request) *YagoSpan {
+
+	span := &YagoSpan{
+		tracer: t,
+		name:   r.Method + " " + r.URL.Path,
+		kind:   SpanKindServer,
+		start:  time.Now(),
+		flags:  traceFlagSampled,
+	}
+	if traceID, parentID, flags, ok := parseTraceParent(r.Header.Get(traceParentHeader)); ok {
+		span.traceID, span.parentID, span.flags = traceID, parentID, flags
+		span.traceState = r.Header.Get(traceStateHeader)
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.target", r.URL.Path)
+	return span
+}
+
+// YagoSpan is a timed operation of a trace, every method is a no-op on a nil span,
+// so handlers need not check whether tracing is enabled
+type YagoSpan struct {
+	tracer *YagoTracer
+
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	flags      byte
+	traceState string
+
+	name       string
+	kind       YagoSpanKind
+	start      time.Time
+	end        time.Time
+	attributes [][2]string
+	err        string
+}
+
+// StartChild starts a span of the same trace below s, eg: a call to another service
+func (s *YagoSpan) StartChild(name string) *YagoSpan {
+	if s == nil {
+		return nil
+	}
+	child := &YagoSpan{
+		tracer:     s.tracer,
+		traceID:    s.traceID,
+		parentID:   s.spanID,
+		flags:      s.flags,
+		traceState: s.traceState,
+		name:       name,
+		kind:       SpanKindInternal,
+		start:      time.Now(),
+	}
+	rand.Read(child.spanID[:])
+	return child
+}
+
+// SetKind marks s as a client span when it calls another service
+func (s *YagoSpan) SetKind(kind YagoSpanKind) {
+	if s != nil {
+		s.kind = kind
+	}
+}
+
+func (s *YagoSpan) SetAttribute(key, value string) {
+	if s != nil {
+		s.attributes = append(s.attributes, [2]string{key, value})
+	}
+}
+
+// SetError marks s as failed
+func (s *YagoSpan) SetError(err error) {
+	if s != nil && err != nil {
+		s.err = err.Error()
+	}
+}
+
+// End ends s and exports it when the trace is sampled, spans end only once
+func (s *YagoSpan) End() {
+	if s == nil || !s.end.IsZero() {
+		return
+	}
+	s.end = time.Now()
+	if s.flags&traceFlagSampled == 0 {
+		return
+	}
+	if err := s.tracer.exporter.Export(s); err != nil {
+		s.tracer.logger.Warn("[YagoTracer] Export span fail", "span", s.name, "err", err)
+	}
+}
+
+func (s *YagoSpan) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+func (s *YagoSpan) SpanID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.spanID[:])
+}
+
+func (s *YagoSpan) Name() string {
+	if s == nil {
+		return ""
+	}
+	return s.name
+}
+
+// ParentSpanID returns the span id of the parent of s, empty for a root span
+func (s *YagoSpan) ParentSpanID() string {
+	if s == nil || s.parentID == [8]byte{} {
+		return ""
+	}
+	return hex.EncodeToString(s.parentID[:])
+}
+
+// TraceState returns the tracestate header of the trace of s
+func (s *YagoSpan) TraceState() string {
+	if s == nil {
+		return ""
+	}
+	return s.traceState
+}
+
+func (s *YagoSpan) Kind() YagoSpanKind {
+	if s == nil {
+		return 0
+	}
+	return s.kind
+}
+
+func (s *YagoSpan) StartTime() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	return s.start
+}
+
+// EndTime returns the end of s, zero until s ends
+func (s *YagoSpan) EndTime() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	return s.end
+}
+
+// Attributes returns a copy of the key value pairs of s in the order they were set
+func (s *YagoSpan) Attributes() [][2]string {
+	if s == nil {
+		return nil
+	}
+	return append([][2]string{}, s.attributes...)
+}
+
+// Err returns the error message set by SetError, empty when s did not fail
+func (s *YagoSpan) Err() string {
+	if s == nil {
+		return ""
+	}
+	return s.err
+}
+
+// TraceParent returns the traceparent header value of s, eg: 00-<trace id>-<span id>-01
+func (s *YagoSpan) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return "00-" + s.TraceID() + "-" + s.SpanID() + "-" + hex.EncodeToString([]byte{s.flags})
+}
+
+// Inject sets the traceparent and tracestate headers of an outgoing request to continue
+// the trace of s, eg: span.Inject(req.Header)
+func (s *YagoSpan) Inject(h http.Header) {
+	if s == nil {
+		return
+	}
+	h.Set(traceParentHeader, s.TraceParent())
+	if s.traceState != "" {
+		h.Set(traceStateHeader, s.traceState)
+	}
+}
+
+// parseTraceParent parses a version 00 traceparent header, higher versions are parsed by
+// their 00 prefix as the spec asks
+func parseTraceParent(v string) (traceID [16]byte, parentID [8]byte, flags byte, ok bool) {
+
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return
+	}
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return
+	}
+	for _, p := range parts[:4] {
+		if strings.ToLower(p) != p {
+			return
+		}
+	}
+
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || traceID == [16]byte{} {
+		return
+	}
+	if _, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil || parentID == [8]byte{} {
+		return
+	}
+	bs, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return
+	}
+	return traceID, parentID, bs[0], true
+}
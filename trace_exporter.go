@@ -0,0 +1,142 @@
+package yago
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// YagoMemoryExporter keeps exported spans in memory, eg: for tests and local debugging
+type YagoMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*YagoSpan
+}
+
+func (e *YagoMemoryExporter) Export(span *YagoSpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+// Spans returns the exported spans in the order they ended
+func (e *YagoMemoryExporter) Spans() []*YagoSpan {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*YagoSpan{}, e.spans...)
+}
+
+func (e *YagoMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// YagoOTLPFileExporter appends every span as a line of OTLP JSON, the format of the
+// OpenTelemetry collector file exporter, which otelcol and most trace viewers read
+type YagoOTLPFileExporter struct {
+	serviceName string
+	f           *os.File
+	mu          sync.Mutex
+}
+
+func NewYagoOTLPFileExporter(path, serviceName string) (*YagoOTLPFileExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &YagoOTLPFileExporter{serviceName: serviceName, f: f}, nil
+}
+
+func (e *YagoOTLPFileExporter) Export(span *YagoSpan) error {
+	bs, err := json.Marshal(otlpTraces(e.serviceName, span))
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.f.Write(append(bs, '\n'))
+	return err
+}
+
+func (e *YagoOTLPFileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.f.Close()
+}
+
+// otlp* are the parts of ExportTraceServiceRequest in OTLP JSON encoding
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	TraceState        string          `json:"traceState,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTracesData struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpTraces(serviceName string, span *YagoSpan) *otlpTracesData {
+
+	s := otlpSpan{
+		TraceID:           span.TraceID(),
+		SpanID:            span.SpanID(),
+		ParentSpanID:      span.ParentSpanID(),
+		TraceState:        span.TraceState(),
+		Name:              span.Name(),
+		Kind:              int(span.Kind()),
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime().UnixNano(), 10),
+	}
+	for _, kv := range span.Attributes() {
+		s.Attributes = append(s.Attributes, otlpAttribute{Key: kv[0], Value: otlpValue{StringValue: kv[1]}})
+	}
+	if err := span.Err(); err != "" {
+		// STATUS_CODE_ERROR
+		s.Status = otlpStatus{Code: 2, Message: err}
+	}
+
+	scope := otlpScopeSpans{Spans: []otlpSpan{s}}
+	scope.Scope.Name = "github.com/graceful-go/yago"
+
+	resource := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	resource.Resource.Attributes = []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: serviceName}}}
+
+	return &otlpTracesData{ResourceSpans: []otlpResourceSpans{resource}}
+}
@@ -0,0 +1,183 @@
+package yago
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceParent(t *testing.T) {
+
+	uts := []struct {
+		header string
+		ok     bool
+		flags  byte
+	}{
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: true, flags: 1},
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", ok: true, flags: 0},
+		{header: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", ok: true, flags: 1},
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", ok: false},
+		{header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: false},
+		{header: "0g-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: false},
+		{header: "zz-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: false},
+		{header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", ok: false},
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", ok: false},
+		{header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", ok: false},
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01", ok: false},
+		{header: "", ok: false},
+	}
+
+	for _, ut := range uts {
+		traceID, parentID, flags, ok := parseTraceParent(ut.header)
+		assert.Equal(t, ut.ok, ok, ut.header)
+		if ut.ok {
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", (&YagoSpan{traceID: traceID}).TraceID())
+			assert.Equal(t, "00f067aa0ba902b7", (&YagoSpan{spanID: parentID}).SpanID())
+			assert.Equal(t, ut.flags, flags)
+		}
+	}
+}
+
+func TestYagoTracing(t *testing.T) {
+
+	exporter := &YagoMemoryExporter{}
+
+	aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/a/", Timeout: 1000})
+	var handlerSpan *YagoSpan
+	assert.Nil(t, aServer.Register("apidemo", func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) {
+		handlerSpan = ctx.Span().StartChild("call db")
+		handlerSpan.End()
+		return &DemoRsp{}, nil
+	}))
+
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithTracing(&YagoTraceConfig{Exporter: exporter}), WithApiServer(aServer))
+	assert.Nil(t, err)
+
+	// a traceparent continues the trace of the caller
+	r := httptest.NewRequest(http.MethodPost, "/a/apidemo", strings.NewReader(`{}`))
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set("tracestate", "vendor=value")
+	w := httptest.NewRecorder()
+	y.ServeHTTP(w, r)
+
+	spans := exporter.Spans()
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID())
+	}
+	assert.Equal(t, []string{"route", "read body", "decode", "call db", "invoke apidemo", "POST /a/apidemo"}, names)
+
+	root := spans[len(spans)-1]
+	assert.Equal(t, "00f067aa0ba902b7", otlpTraces("yago", root).ResourceSpans[0].ScopeSpans[0].Spans[0].ParentSpanID)
+	assert.Equal(t, root.TraceParent(), w.Header().Get("traceparent"))
+	assert.Equal(t, "vendor=value", w.Header().Get("tracestate"))
+	assert.Equal(t, root.SpanID(), spans[1].ParentSpanID())
+	// spans started by the handler are children of the invoke span
+	assert.Equal(t, spans[4].SpanID(), handlerSpan.ParentSpanID())
+
+	// unsampled traces are propagated but never exported
+	exporter.Reset()
+	r = httptest.NewRequest(http.MethodPost, "/a/apidemo", strings.NewReader(`{}`))
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	w = httptest.NewRecorder()
+	y.ServeHTTP(w, r)
+	assert.Empty(t, exporter.Spans())
+	assert.True(t, strings.HasSuffix(w.Header().Get("traceparent"), "-00"))
+
+	// requests without traceparent start a new trace
+	w = httptest.NewRecorder()
+	y.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/none", nil))
+	spans = exporter.Spans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, spans[1].TraceParent(), w.Header().Get("traceparent"))
+	assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[1].TraceID())
+}
+
+func TestYagoOTLPFileExporter(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "spans.json")
+	tracer, err := NewYagoTracer(&YagoTraceConfig{ServiceName: "demo", File: file})
+	assert.Nil(t, err)
+
+	root := tracer.startRequest(httptest.NewRequest(http.MethodGet, "/p/item", nil))
+	child := root.StartChild("render")
+	child.SetError(os.ErrNotExist)
+	child.End()
+	root.End()
+	assert.Nil(t, tracer.Close())
+
+	f, err := os.Open(file)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	var lines []*otlpTracesData
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		data := &otlpTracesData{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), data))
+		lines = append(lines, data)
+	}
+	assert.Len(t, lines, 2)
+
+	assert.Equal(t, "demo", lines[0].ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+	span := lines[0].ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "render", span.Name)
+	assert.Equal(t, root.SpanID(), span.ParentSpanID)
+	assert.Equal(t, 2, span.Status.Code)
+
+	span = lines[1].ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "GET /p/item", span.Name)
+	assert.Equal(t, int(SpanKindServer), span.Kind)
+	assert.Empty(t, span.ParentSpanID)
+}
+
+func TestYagoSpanAccessors(t *testing.T) {
+
+	exporter := &YagoMemoryExporter{}
+	tracer, err := NewYagoTracer(&YagoTraceConfig{Exporter: exporter})
+	assert.Nil(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/p/item", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set("tracestate", "vendor=value")
+	root := tracer.startRequest(r)
+	child := root.StartChild("call db")
+	child.SetKind(SpanKindClient)
+	child.SetError(os.ErrNotExist)
+	assert.True(t, child.EndTime().IsZero())
+	child.End()
+
+	assert.Equal(t, "00f067aa0ba902b7", root.ParentSpanID())
+	assert.Equal(t, root.SpanID(), child.ParentSpanID())
+	assert.Equal(t, "vendor=value", child.TraceState())
+	assert.Equal(t, SpanKindServer, root.Kind())
+	assert.Equal(t, SpanKindClient, child.Kind())
+	assert.False(t, child.EndTime().Before(child.StartTime()))
+	assert.Equal(t, os.ErrNotExist.Error(), child.Err())
+	assert.Empty(t, root.Err())
+
+	attributes := root.Attributes()
+	assert.Equal(t, [][2]string{{"http.method", "GET"}, {"http.target", "/p/item"}}, attributes)
+	attributes[0][1] = "POST"
+	assert.Equal(t, "GET", root.Attributes()[0][1])
+
+	// a root span of a new trace has no parent
+	assert.Empty(t, tracer.startRequest(httptest.NewRequest(http.MethodGet, "/", nil)).ParentSpanID())
+
+	var none *YagoSpan
+	assert.Empty(t, none.ParentSpanID())
+	assert.Empty(t, none.TraceState())
+	assert.Empty(t, none.Attributes())
+	assert.Empty(t, none.Err())
+	assert.Equal(t, YagoSpanKind(0), none.Kind())
+	assert.True(t, none.StartTime().IsZero())
+	assert.True(t, none.EndTime().IsZero())
+}
@@ -0,0 +1,367 @@
+package yago
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultUploadMaxFileSize    = 10 << 20
+	defaultUploadMaxRequestSize = 32 << 20
+	defaultUploadMaxFiles       = 10
+
+	// uploadMaxValueSize bounds non file form values
+	uploadMaxValueSize = 1 << 20
+
+	// uploadSniffSize is the count of bytes http.DetectContentType considers
+	uploadSniffSize = 512
+
+	// uploadActiveExt is the extension of files a browser would run, eg: html or svg,
+	// they are served as application/octet-stream so they never run on the site origin
+	uploadActiveExt = ".bin"
+)
+
+func init() {
+	// files without a known extension are sniffed by http.ServeContent, so .bin must be known
+	if mime.TypeByExtension(uploadActiveExt) == "" {
+		mime.AddExtensionType(uploadActiveExt, "application/octet-stream")
+	}
+}
+
+var (
+	ErrUploadNotMultipart = errors.New("upload request is not multipart/form-data")
+	ErrUploadTooLarge     = errors.New("upload file too large")
+	ErrUploadTooMany      = errors.New("too many upload files")
+	ErrUploadType         = errors.New("upload file type not allowed")
+)
+
+// YagoStorage stores uploaded files by name
+type YagoStorage interface {
+	Save(ctx context.Context, name string, r io.Reader) (int64, error)
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// YagoLocalStorage stores files in a local directory, which can be served by a YagoFileServer
+type YagoLocalStorage struct {
+	Dir string
+}
+
+func NewYagoLocalStorage(dir string) (*YagoLocalStorage, error) {
+	if dir == "" {
+		return nil, errors.New("empty storage dir is not allowed")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &YagoLocalStorage{Dir: dir}, nil
+}
+
+// Save writes r to a temp file first and renames it, so a failed upload never leaves a partial file
+func (y *YagoLocalStorage) Save(ctx context.Context, name string, r io.Reader) (int64, error) {
+
+	dst, err := y.path(name)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.CreateTemp(y.Dir, ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return 0, err
+	}
+	return n, nil
+}
+
+func (y *YagoLocalStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	p, err := y.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (y *YagoLocalStorage) Delete(ctx context.Context, name string) error {
+	p, err := y.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+func (y *YagoLocalStorage) path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name || strings.ContainsAny(name, `/\`) {
+		return "", errors.New("invalid storage file name: " + name)
+	}
+	return filepath.Join(y.Dir, name), nil
+}
+
+// YagoUploadConfig
+// limits multipart uploads handled by YagoUploader
+type YagoUploadConfig struct {
+	// Field is the form field of files, empty accepts files of any field
+	Field string `json:"field"`
+
+	// MaxFileSize is the max bytes of each file, default 10MB
+	MaxFileSize int64 `json:"maxFileSize"`
+
+	// MaxRequestSize is the max bytes of the whole request, default 32MB
+	MaxRequestSize int64 `json:"maxRequestSize"`
+
+	// MaxFiles is the max count of files of a request, default 10
+	MaxFiles int `json:"maxFiles"`
+
+	// AllowedTypes are content type prefixes sniffed from file content, eg: image/, empty allows any
+	AllowedTypes []string `json:"allowedTypes"`
+
+	// PublicRoute is the route of the YagoFileServer serving the storage, eg: uploads,
+	// uploaded files get the url /uploads/<name>
+	PublicRoute string `json:"publicRoute"`
+}
+
+type YagoUploadedFile struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType"`
+	URL         string `json:"url,omitempty"`
+}
+
+type YagoUploadResult struct {
+	Files  []*YagoUploadedFile
+	Values map[string]string
+}
+
+type YagoUploader struct {
+	c       *YagoUploadConfig
+	storage YagoStorage
+}
+
+func NewYagoUploader(c *YagoUploadConfig, storage YagoStorage) (*YagoUploader, error) {
+	if c == nil || storage == nil {
+		return nil, errors.New("empty upload config or storage is not allowed")
+	}
+	return &YagoUploader{c: c, storage: storage}, nil
+}
+
+// Save streams the files of a multipart request into the storage under generated names,
+// files already saved are deleted when any file fails
+func (y *YagoUploader) Save(yc *YagoContext) (*YagoUploadResult, error) {
+
+	if !isMultipartRequest(yc.r) {
+		return nil, ErrUploadNotMultipart
+	}
+
+	maxRequestSize := y.c.MaxRequestSize
+	if maxRequestSize <= 0 {
+		maxRequestSize = defaultUploadMaxRequestSize
+	}
+	yc.r.Body = http.MaxBytesReader(yc.w, yc.r.Body, maxRequestSize)
+
+	mr, err := yc.r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &YagoUploadResult{Values: make(map[string]string)}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			y.cleanup(yc, result)
+			return nil, err
+		}
+
+		if part.FileName() == "" {
+			bs, err := io.ReadAll(io.LimitReader(part, uploadMaxValueSize))
+			part.Close()
+			if err != nil {
+				y.cleanup(yc, result)
+				return nil, err
+			}
+			result.Values[part.FormName()] = string(bs)
+			continue
+		}
+
+		if y.c.Field != "" && part.FormName() != y.c.Field {
+			part.Close()
+			continue
+		}
+
+		file, err := y.saveFile(yc, part.FormName(), part.FileName(), part, len(result.Files))
+		part.Close()
+		if err != nil {
+			y.cleanup(yc, result)
+			return nil, err
+		}
+		result.Files = append(result.Files, file)
+	}
+}
+
+func (y *YagoUploader) saveFile(yc *YagoContext, field, filename string, r io.Reader, saved int) (*YagoUploadedFile, error) {
+
+	maxFiles := y.c.MaxFiles
+	if maxFiles <= 0 {
+		maxFiles = defaultUploadMaxFiles
+	}
+	if saved >= maxFiles {
+		return nil, ErrUploadTooMany
+	}
+
+	head := make([]byte, uploadSniffSize)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	ctype := http.DetectContentType(head)
+	if !y.allowed(ctype) {
+		return nil, ErrUploadType
+	}
+
+	maxFileSize := y.c.MaxFileSize
+	if maxFileSize <= 0 {
+		maxFileSize = defaultUploadMaxFileSize
+	}
+
+	file := &YagoUploadedFile{
+		Field:       field,
+		Filename:    path.Base(strings.ReplaceAll(filename, `\`, "/")),
+		Name:        newUploadName(filename, ctype),
+		ContentType: ctype,
+	}
+	body := &yagoMaxSizeReader{r: io.MultiReader(bytes.NewReader(head), r), left: maxFileSize}
+	size, err := y.storage.Save(yc, file.Name, body)
+	if err != nil {
+		return nil, err
+	}
+	file.Size = size
+	if y.c.PublicRoute != "" {
+		file.URL = joinRoute(y.c.PublicRoute, file.Name)
+	}
+	return file, nil
+}
+
+func (y *YagoUploader) allowed(ctype string) bool {
+	if len(y.c.AllowedTypes) == 0 {
+		return true
+	}
+	for _, v := range y.c.AllowedTypes {
+		if strings.HasPrefix(ctype, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (y *YagoUploader) cleanup(yc *YagoContext, result *YagoUploadResult) {
+	for _, f := range result.Files {
+		y.storage.Delete(yc, f.Name)
+	}
+}
+
+// newUploadName generates a random file name with an extension of the sniffed content type,
+// the extension of filename is kept only when it maps to that type, so a file is never
+// served as another type than its content, eg: a png named x.html is stored as .png,
+// active content is stored as .bin whatever its name, eg: html, xml and svg
+func newUploadName(filename, ctype string) string {
+
+	bs := make([]byte, 16)
+	rand.Read(bs)
+	name := hex.EncodeToString(bs)
+
+	if isActiveType(ctype) {
+		return name + uploadActiveExt
+	}
+
+	ext := strings.ToLower(path.Ext(strings.ReplaceAll(filename, `\`, "/")))
+	if isSafeExt(ext) && sameMediaType(mime.TypeByExtension(ext), ctype) {
+		return name + ext
+	}
+	if exts, _ := mime.ExtensionsByType(ctype); len(exts) > 0 {
+		return name + exts[0]
+	}
+	return name
+}
+
+func sameMediaType(a, b string) bool {
+	ma, _, errA := mime.ParseMediaType(a)
+	mb, _, errB := mime.ParseMediaType(b)
+	return errA == nil && errB == nil && ma == mb
+}
+
+// isActiveType reports content types browsers render or run, eg: text/html, image/svg+xml
+func isActiveType(ctype string) bool {
+	mt, _, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return true
+	}
+	for _, v := range []string{"html", "xml", "javascript", "ecmascript", "flash"} {
+		if strings.Contains(mt, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeExt(ext string) bool {
+	if len(ext) < 2 || len(ext) > 10 {
+		return false
+	}
+	for _, c := range ext[1:] {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isMultipartRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// yagoMaxSizeReader fails with ErrUploadTooLarge once more than left bytes are read
+type yagoMaxSizeReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (y *yagoMaxSizeReader) Read(p []byte) (int, error) {
+	if y.left < 0 {
+		return 0, ErrUploadTooLarge
+	}
+	if int64(len(p)) > y.left+1 {
+		p = p[:y.left+1]
+	}
+	n, err := y.r.Read(p)
+	y.left -= int64(n)
+	if y.left < 0 {
+		return n, ErrUploadTooLarge
+	}
+	return n, err
+}
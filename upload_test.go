@@ -0,0 +1,149 @@
+package yago
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoUploader(t *testing.T) {
+
+	dir := t.TempDir()
+	storage, err := NewYagoLocalStorage(dir)
+	assert.Nil(t, err)
+
+	uploader, err := NewYagoUploader(&YagoUploadConfig{
+		Field:        "file",
+		MaxFileSize:  1024,
+		AllowedTypes: []string{"image/"},
+		PublicRoute:  "uploads",
+	}, storage)
+	assert.Nil(t, err)
+
+	aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/a/", Timeout: 1000})
+	assert.Nil(t, aServer.Register("upload", func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) {
+		result, err := uploader.Save(ctx)
+		if err != nil {
+			return &DemoRsp{Field: err.Error()}, nil
+		}
+		return &DemoRsp{Field: result.Files[0].URL + "|" + result.Values["title"]}, nil
+	}))
+	fServer, err := NewYagoFileServer(&YagoFileServerConfig{Dir: dir, Route: "uploads"})
+	assert.Nil(t, err)
+
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithApiServer(aServer), WithFileServer(fServer))
+	assert.Nil(t, err)
+
+	png := append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), bytes.Repeat([]byte{0}, 64)...)
+
+	var uts = []struct {
+		Filename string
+		Data     []byte
+		Expect   string
+	}{
+		{Filename: "../../avatar.PNG", Data: png, Expect: `^/uploads/[0-9a-f]{32}\.png\|avatar$`},
+		// the extension follows the sniffed content, a png is never served as html
+		{Filename: "avatar.html", Data: png, Expect: `^/uploads/[0-9a-f]{32}\.png\|avatar$`},
+		{Filename: "avatar.png", Data: []byte("plain text"), Expect: ErrUploadType.Error()},
+		{Filename: "avatar.png", Data: append(png, bytes.Repeat([]byte{0}, 1024)...), Expect: ErrUploadTooLarge.Error()},
+	}
+
+	for _, ut := range uts {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		mw.WriteField("title", "avatar")
+		fw, _ := mw.CreateFormFile("file", ut.Filename)
+		fw.Write(ut.Data)
+		mw.Close()
+
+		r := httptest.NewRequest(http.MethodPost, "/a/upload", body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		y.ServeHTTP(w, r)
+
+		var rsp struct {
+			Data DemoRsp `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rsp))
+		assert.Regexp(t, ut.Expect, rsp.Data.Field)
+
+		if ut.Data[0] != png[0] || len(ut.Data) > len(png) {
+			continue
+		}
+		w = httptest.NewRecorder()
+		y.ServeHTTP(w, httptest.NewRequest(http.MethodGet, rsp.Data.Field[:len(rsp.Data.Field)-len("|avatar")], nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, png, w.Body.Bytes())
+	}
+
+	// failed uploads leave no files behind
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+}
+
+func TestYagoUploaderActiveContent(t *testing.T) {
+
+	dir := t.TempDir()
+	storage, err := NewYagoLocalStorage(dir)
+	assert.Nil(t, err)
+
+	// no AllowedTypes accepts any file, active content must still never be served as such
+	uploader, err := NewYagoUploader(&YagoUploadConfig{Field: "file", PublicRoute: "uploads"}, storage)
+	assert.Nil(t, err)
+
+	aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/a/", Timeout: 1000})
+	assert.Nil(t, aServer.Register("upload", func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) {
+		result, err := uploader.Save(ctx)
+		if err != nil {
+			return &DemoRsp{Field: err.Error()}, nil
+		}
+		return &DemoRsp{Field: result.Files[0].URL}, nil
+	}))
+	fServer, err := NewYagoFileServer(&YagoFileServerConfig{Dir: dir, Route: "uploads"})
+	assert.Nil(t, err)
+
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithApiServer(aServer), WithFileServer(fServer))
+	assert.Nil(t, err)
+
+	var uts = []struct {
+		Filename string
+		Data     string
+	}{
+		{Filename: "x.html", Data: `<html><script>alert(1)</script></html>`},
+		{Filename: "x.txt", Data: `<!DOCTYPE html><script>alert(1)</script>`},
+		{Filename: "x.xml", Data: `<?xml version="1.0"?><x/>`},
+		{Filename: "x.svg", Data: `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`},
+	}
+
+	for _, ut := range uts {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		fw, _ := mw.CreateFormFile("file", ut.Filename)
+		fw.Write([]byte(ut.Data))
+		mw.Close()
+
+		r := httptest.NewRequest(http.MethodPost, "/a/upload", body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		y.ServeHTTP(w, r)
+
+		var rsp struct {
+			Data DemoRsp `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rsp))
+		assert.Regexp(t, `^/uploads/[0-9a-f]{32}\.bin$`, rsp.Data.Field, ut.Filename)
+
+		w = httptest.NewRecorder()
+		y.ServeHTTP(w, httptest.NewRequest(http.MethodGet, rsp.Data.Field, nil))
+		assert.Equal(t, http.StatusOK, w.Code, ut.Filename)
+		assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"), ut.Filename)
+	}
+}
@@ -0,0 +1,71 @@
+package yago
+
+import (
+	"errors"
+	"fmt"
+)
+
+// YagoRouteNamer is implemented by handlers serving named routes,
+// RouteNames returns route name to absolute url pattern, eg: item => /app/todo/{id}
+type YagoRouteNamer interface {
+	RouteNames() map[string]string
+}
+
+// yagoAssetResolver is implemented by handlers serving static assets
+type yagoAssetResolver interface {
+	resolveAsset(p string) (string, bool)
+}
+
+// yagoBinder is implemented by handlers which need the Yago they are mounted on
+type yagoBinder interface {
+	bind(y *Yago)
+}
+
+// URL builds the path of a named route of any handler, params are key value pairs
+// filling the route params, the rest of them are appended as query string,
+// eg: y.URL("item", "id", 1, "tab", "detail") builds /app/todo/1?tab=detail
+func (y *Yago) URL(name string, params ...interface{}) (string, error) {
+
+	kvs, err := pairsToParams(params)
+	if err != nil {
+		return "", err
+	}
+
+	var pattern string
+	for _, h := range y.handlerList() {
+		namer, ok := h.(YagoRouteNamer)
+		if !ok {
+			continue
+		}
+		p, ok := namer.RouteNames()[name]
+		if !ok {
+			continue
+		}
+		if pattern != "" && pattern != p {
+			return "", fmt.Errorf("ambiguous route name %q: %s and %s", name, pattern, p)
+		}
+		pattern = p
+	}
+	if pattern == "" {
+		return "", errors.New("undefined route name: " + name)
+	}
+
+	route, err := newYagoRoute(name, pattern)
+	if err != nil {
+		return "", err
+	}
+	return route.build(kvs)
+}
+
+// Asset returns the url of a static asset served by a file server, fingerprinted
+// when the file server enables Fingerprint, eg: /static/css/base.css => /static/css/base.3f9a1c.css
+func (y *Yago) Asset(p string) string {
+	for _, h := range y.handlerList() {
+		if r, ok := h.(yagoAssetResolver); ok {
+			if u, ok := r.resolveAsset(p); ok {
+				return u
+			}
+		}
+	}
+	return p
+}
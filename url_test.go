@@ -0,0 +1,87 @@
+package yago
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoURL(t *testing.T) {
+
+	aServer, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/a/"})
+	assert.Nil(t, aServer.Register("apidemo", func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) { return nil, nil }))
+
+	fsys := fstest.MapFS{
+		"page.layout": {Data: []byte(`{{url "a/apidemo"}} {{url "static" "path" "css/base.css"}}`)},
+	}
+	tServer := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/app",
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "item", Path: "/todo/{id}", Templates: []string{"page.layout"}}},
+	}, fsys)
+	assert.Nil(t, tServer.Register("item", func(ctx *YagoContext) (interface{}, error) { return nil, nil }))
+
+	fServer, _ := NewYagoFileServer(&YagoFileServerConfig{FS: fsys, Route: "static"})
+
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithApiServer(aServer), WithTemplateServer(tServer), WithFileServer(fServer))
+	assert.Nil(t, err)
+
+	var uts = []struct {
+		Name      string
+		Params    []interface{}
+		Expect    string
+		ExpectErr bool
+	}{
+		{Name: "a/apidemo", Expect: "/a/apidemo"},
+		{Name: "item", Params: []interface{}{"id", 3, "tab", "detail"}, Expect: "/app/todo/3?tab=detail"},
+		{Name: "static", Params: []interface{}{"path", "css/base.css"}, Expect: "/static/css/base.css"},
+		{Name: "item", ExpectErr: true},
+		{Name: "item", Params: []interface{}{"id"}, ExpectErr: true},
+		{Name: "apidemo", ExpectErr: true},
+		{Name: "none", ExpectErr: true},
+	}
+	for _, uc := range uts {
+		u, err := y.URL(uc.Name, uc.Params...)
+		assert.Equal(t, uc.ExpectErr, err != nil, uc.Name)
+		assert.Equal(t, uc.Expect, u, uc.Name)
+	}
+
+	w := httptest.NewRecorder()
+	y.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app/todo/3", nil))
+	assert.Equal(t, "/a/apidemo /static/css/base.css", w.Body.String())
+}
+
+func TestYagoURLVersionedAPI(t *testing.T) {
+
+	handler := func(ctx *YagoContext, in *DemoReq) (*DemoRsp, error) { return nil, nil }
+	v1, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/api/v1/"})
+	assert.Nil(t, v1.Register("user", handler))
+	v2, _ := NewYagoApiServer(&YagoApiServerConfig{Route: "/api/v2/"})
+	assert.Nil(t, v2.Register("user", handler))
+
+	tServer := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/",
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "user", Path: "/user/{id}", Templates: []string{"page.layout"}}},
+	}, fstest.MapFS{"page.layout": {Data: []byte(`user`)}})
+	assert.Nil(t, tServer.Register("user", func(ctx *YagoContext) (interface{}, error) { return nil, nil }))
+
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithApiServer(v1), WithApiServer(v2), WithTemplateServer(tServer))
+	assert.Nil(t, err)
+
+	var uts = []struct {
+		Name   string
+		Params []interface{}
+		Expect string
+	}{
+		{Name: "api/v1/user", Expect: "/api/v1/user"},
+		{Name: "api/v2/user", Expect: "/api/v2/user"},
+		{Name: "user", Params: []interface{}{"id", 1}, Expect: "/user/1"},
+	}
+	for _, uc := range uts {
+		u, err := y.URL(uc.Name, uc.Params...)
+		assert.Nil(t, err, uc.Name)
+		assert.Equal(t, uc.Expect, u, uc.Name)
+	}
+}
@@ -2,16 +2,27 @@ package yago
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// defaultShutdownTimeout is the ShutdownTimeout of configs without one, in milliseconds
+const defaultShutdownTimeout = 5000
+
 // YagoConfig
 type YagoConfig struct {
 	Port    uint32 `json:"port" default:"8080"`
 	Timeout uint32 `json:"timeout" default:"1000"`
+
+	// ShutdownTimeout is how long in milliseconds Start waits for requests in flight
+	// once ctx is done, default 5000
+	ShutdownTimeout uint32 `json:"shutdownTimeout" default:"5000"`
 }
 
 type YagoHandler interface {
@@ -20,59 +31,222 @@ type YagoHandler interface {
 	Type() string
 }
 
+// yagoWatcher is implemented by handlers that need a background loop
+// bound to the server lifetime, eg: template hot reload in DevMode
+type yagoWatcher interface {
+	Watch(ctx context.Context)
+}
+
 type Yago struct {
-	cfg      *YagoConfig
-	handlers []YagoHandler
-	logger   Logger
-	paths    map[string]YagoHandler
-	mu       sync.RWMutex
+	cfg       *YagoConfig
+	handlers  []YagoHandler
+	logger    Logger
+	i18n      *YagoI18n
+	accessLog *YagoAccessLog
+	metrics   *YagoMetrics
+	tracer    *YagoTracer
+	panicHook YagoPanicHook
+	health    *YagoHealth
+	paths     map[string]YagoHandler
+	mu        sync.RWMutex
+
+	// configFile is reloaded on SIGHUP when Yago is built by NewFromConfigFile
+	configFile string
+	reloadMu   sync.Mutex
+
+	// shuttingDown fails readiness once Start begins graceful shutdown
+	shuttingDown atomic.Bool
 }
 
 func New(opts ...Option) (*Yago, error) {
 
-	logger := &DefaultLogger{}
-
 	y := &Yago{
-		logger: logger,
+		logger: &DefaultLogger{},
 		paths:  make(map[string]YagoHandler),
 	}
+	var errs []error
 	for _, opt := range opts {
-		opt(y)
+		if err := opt(y); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	if err := y.check(); err != nil {
+	for _, h := range y.handlers {
+		if b, ok := h.(yagoBinder); ok {
+			b.bind(y)
+		}
+	}
+
+	if y.tracer != nil {
+		y.tracer.logger = y.logger
+	}
+
+	if err := y.check(errs...); err != nil {
 		return nil, err
 	}
 
 	return y, nil
 }
 
-func (y *Yago) check() error {
-	return nil
+// Start will block current process and start up a http server,
+// it shuts the server down gracefully once ctx is done
+func (y *Yago) Start(ctx context.Context) error {
+	// routes of handlers registered after New, eg: by NewFromConfigFile, are checked here,
+	// ApplyConfig swaps the config and handlers checked under reloadMu
+	y.reloadMu.Lock()
+	err := y.check()
+	y.reloadMu.Unlock()
+	if err != nil {
+		y.logger.Error("[YagoServer] Server Startup fail", "err", err)
+		return err
+	}
+	cfg := y.config()
+	y.logger.Info("[YagoServer] Server Startup", "port", cfg.Port)
+	if y.configFile != "" {
+		go y.watchReload(ctx)
+	}
+	for _, h := range y.handlerList() {
+		if w, ok := h.(yagoWatcher); ok {
+			go w.Watch(ctx)
+		}
+	}
+
+	server := &http.Server{Addr: fmt.Sprintf(":%d", cfg.Port), Handler: y}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	return y.shutdown(server)
 }
 
-// Start will block current process and start up a http server
-func (y *Yago) Start(ctx context.Context) error {
-	y.logger.Log("[YagoServer] Server Startup on port", y.cfg.Port)
-	return http.ListenAndServe(fmt.Sprintf(":%d", y.cfg.Port), y)
+// shutdown fails readiness, waits the ShutdownDelay of health for load balancers to
+// notice, then waits ShutdownTimeout for requests in flight
+func (y *Yago) shutdown(server *http.Server) error {
+
+	// a reload on SIGHUP may swap the config until Start returns
+	cfg := y.config()
+	y.shuttingDown.Store(true)
+	y.logger.Info("[YagoServer] Server Shutdown", "port", cfg.Port)
+
+	if y.health != nil && y.health.c.ShutdownDelay > 0 {
+		time.Sleep(time.Millisecond * time.Duration(y.health.c.ShutdownDelay))
+	}
+
+	timeout := cfg.ShutdownTimeout
+	if timeout == 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(timeout))
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if y.accessLog != nil {
+		y.accessLog.Close()
+	}
+	if y.tracer != nil {
+		y.tracer.Close()
+	}
+	if err != nil {
+		y.logger.Error("[YagoServer] Server Shutdown fail", "err", err)
+		return err
+	}
+	y.logger.Info("[YagoServer] Server Shutdown succ")
+	return nil
 }
 
 func (y *Yago) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r, info := withRequestInfo(r)
+	w.Header().Set(RequestIDHeader, info.id)
+	if y.tracer != nil {
+		info.span = y.tracer.startRequest(r)
+		info.span.Inject(w.Header())
+	}
+
+	routeSpan := info.span.StartChild("route")
 	handler, handlerName := y.findHandler(r.URL.Path)
+	routeSpan.SetAttribute("handler", handlerName)
+	routeSpan.End()
+
+	rw := &yagoResponseWriter{ResponseWriter: w}
+	if y.metrics != nil {
+		y.metrics.begin(handlerName)
+	}
+	defer y.observe(r, rw, info, handlerName, time.Now())
+	defer y.recoverServe(rw, r, info)
+	w = rw
+
 	if handler == nil {
-		y.logger.Loglnf("[Yago] handler not found for path: %s", r.URL.Path)
+		y.logger.Debug("[Yago] handler not found", "requestId", info.id, "path", r.URL.Path)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-	y.logger.Loglnf("[Yago] [%s] prepare handler for path: %s", handlerName, r.URL.Path)
+	y.logger.Debug("[Yago] prepare handler", "requestId", info.id, "handler", handlerName, "path", r.URL.Path)
 	handler.ServeHTTP(w, r)
 }
 
+// observe records a served request in the access log, metrics and its span
+func (y *Yago) observe(r *http.Request, w *yagoResponseWriter, info *yagoRequestInfo, handlerName string, start time.Time) {
+	if info.span != nil {
+		info.span.SetAttribute("http.status_code", strconv.Itoa(w.statusCode()))
+		if info.hasAPICode {
+			info.span.SetAttribute("yago.api_code", strconv.Itoa(info.apiCode))
+		}
+		if w.statusCode() >= http.StatusInternalServerError {
+			info.span.SetError(errors.New(http.StatusText(w.statusCode())))
+		}
+		info.span.End()
+	}
+	if y.accessLog != nil {
+		y.accessLog.log(r, w, info, start)
+	}
+	if y.metrics != nil {
+		y.metrics.end(handlerName, info, w.statusCode(), time.Since(start))
+	}
+}
+
+// recoverServe recovers panics no server recovered, eg: of file servers, and responds 500.
+// A started response is aborted instead, so clients never take a truncated body as complete
+func (y *Yago) recoverServe(w *yagoResponseWriter, r *http.Request, info *yagoRequestInfo) {
+	pe, ok := recoverPanic(recover(), nil).(*YagoPanicError)
+	if !ok {
+		return
+	}
+	reportPanic(y, y.logger.With("requestId", info.id), r, info.id, pe)
+	info.span.SetError(pe)
+	if w.status != 0 {
+		panic(http.ErrAbortHandler)
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// handlerList returns the handlers of the current config, which are replaced on reload
+// config returns the server config, which is swapped by ApplyConfig
+func (y *Yago) config() *YagoConfig {
+	y.mu.RLock()
+	defer y.mu.RUnlock()
+	return y.cfg
+}
+
+func (y *Yago) handlerList() []YagoHandler {
+	y.mu.RLock()
+	defer y.mu.RUnlock()
+	return y.handlers
+}
+
 func (y *Yago) findHandler(p string) (http.Handler, string) {
 
 	y.mu.RLock()
 	if h, ok := y.paths[p]; ok {
 		y.mu.RUnlock()
+		if h == nil {
+			return nil, ""
+		}
 		return h.Handler(), h.Type()
 	}
 	y.mu.RUnlock()
@@ -80,14 +254,46 @@ func (y *Yago) findHandler(p string) (http.Handler, string) {
 	y.mu.Lock()
 	defer y.mu.Unlock()
 
+	// the longest pattern wins, so a handler on / serves the pages no other handler claims
+	var matched YagoHandler
 	for _, h := range y.handlers {
 		if !strings.HasPrefix(p, h.Pattern()) {
 			continue
 		}
-		y.paths[p] = h
-		return h.Handler(), h.Type()
+		if matched == nil || len(h.Pattern()) > len(matched.Pattern()) {
+			matched = h
+		}
+	}
+
+	y.paths[p] = matched
+	if matched == nil {
+		return nil, ""
+	}
+	return matched.Handler(), matched.Type()
+}
+
+// Health returns the health probes, nil without WithHealth, eg: to add checks to
+// servers built by NewFromConfigFile
+func (y *Yago) Health() *YagoHealth {
+	return y.health
+}
+
+// ApiServer returns the api server mounted on route, eg: servers built by NewFromConfigFile
+func (y *Yago) ApiServer(route string) *YagoApiServer {
+	for _, h := range y.handlerList() {
+		if s, ok := h.(*YagoApiServer); ok && s.Pattern() == route {
+			return s
+		}
 	}
+	return nil
+}
 
-	y.paths[p] = nil
-	return nil, ""
+// TemplateServer returns the template server mounted on route
+func (y *Yago) TemplateServer(route string) *YagoTemplateServer {
+	for _, h := range y.handlerList() {
+		if s, ok := h.(*YagoTemplateServer); ok && s.Pattern() == route {
+			return s
+		}
+	}
+	return nil
 }
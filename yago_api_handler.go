@@ -23,7 +23,12 @@ func (y *YagoApiHandler) packIn(bs []byte) (YagoMessage, error) {
 	return nil, errors.New("marshal fail, not YagoMessage found")
 }
 
-func (y *YagoApiHandler) invoke(yc *YagoContext, in YagoMessage) (YagoMessage, error) {
+// invoke calls the handler func, a panic of it is returned as a *YagoPanicError
+func (y *YagoApiHandler) invoke(yc *YagoContext, in YagoMessage) (rsp YagoMessage, err error) {
+
+	defer func() {
+		err = recoverPanic(recover(), err)
+	}()
 
 	if yc == nil || in == nil {
 		return nil, errors.New("[YagoApiHandler] invoke fail, unexpected error occour, invoke params can not be nil value")
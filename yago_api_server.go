@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,6 +20,15 @@ const (
 	CodeYagoAPIServiceNotFound int = -100001
 )
 
+// message keys of YagoAPIWrapper.Msg in i18n catalogs
+const (
+	MsgYagoAPIReqReadError    string = "yago.api.req_read_error"
+	MsgYagoAPIReqParseError   string = "yago.api.req_parse_error"
+	MsgYagoAPIReqParamError   string = "yago.api.req_param_error"
+	MsgYagoAPIInternalError   string = "yago.api.internal_error"
+	MsgYagoAPIServiceNotFound string = "yago.api.service_not_found"
+)
+
 var (
 	_ym    *YagoMessage = new(YagoMessage)
 	_yc    *YagoContext = new(YagoContext)
@@ -30,25 +40,35 @@ type YagoAPIWrapper struct {
 	Code int         `json:"code"`
 	Msg  string      `json:"msg"`
 	Data interface{} `json:"data"`
+
+	// RequestID is the id of the request, users report it to match their errors to logs
+	RequestID string `json:"requestId,omitempty"`
 }
 
 type YagoApiServerConfig struct {
-	Route   string
-	Timeout int
+	Route   string `json:"route"`
+	Timeout int    `json:"timeout"`
 }
 
 type YagoApiServer struct {
-	c        *YagoApiServerConfig
+	// c is swapped on reload, requests keep the config they started with
+	c        atomic.Pointer[YagoApiServerConfig]
 	handlers map[string]*YagoApiHandler
 	logger   Logger
+	yago     *Yago
 }
 
 func NewYagoApiServer(c *YagoApiServerConfig) (*YagoApiServer, error) {
-	return &YagoApiServer{
-		c:        c,
+	y := &YagoApiServer{
 		handlers: make(map[string]*YagoApiHandler),
 		logger:   &DefaultLogger{},
-	}, nil
+	}
+	y.c.Store(c)
+	return y, nil
+}
+
+func (y *YagoApiServer) config() *YagoApiServerConfig {
+	return y.c.Load()
 }
 
 func (y *YagoApiServer) Type() string {
@@ -57,9 +77,8 @@ func (y *YagoApiServer) Type() string {
 
 func (y *YagoApiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
-	y.logger.Loglnf("[YagoApiServer] recv request: %s", r.URL.Path)
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(y.c.Timeout))
+	c := y.config()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(c.Timeout))
 	defer cancel()
 
 	queryString, _ := url.QueryUnescape(r.URL.RawQuery)
@@ -73,28 +92,38 @@ func (y *YagoApiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	yc.query = queryParams
 	yc.w = w
 	yc.r = r
-	yc.route = y.c.Route
+	yc.route = c.Route
 	yc.Context = ctx
-	yc.serviceName = strings.TrimPrefix(r.URL.Path, y.c.Route)
+	yc.serviceName = strings.TrimPrefix(r.URL.Path, c.Route)
+	if y.yago != nil {
+		yc.setLocale(y.yago.i18n)
+	}
+	yc.setLogger(y.logger, requestIDOf(r))
 
-	y.logger.Loglnf("[YagoApiServer] Handle HTTP Request for [%s] %s", method, p)
+	yc.logger.Debug("[YagoApiServer] Handle HTTP Request", "method", method, "path", p)
 
-	if r.Body != nil {
+	if isMultipartRequest(r) {
+		// multipart bodies are streamed by YagoUploader in the handler, the request message is empty
+		yc.body = []byte("{}")
+	} else if r.Body != nil {
+		readSpan := yc.Span().StartChild("read body")
 		bs, err := io.ReadAll(r.Body)
+		readSpan.SetError(err)
+		readSpan.End()
 		if err != nil {
-			y.logger.Loglnf("[YagoApiServer] Handle HTTP Request fail for [%s], err: %s", method, err.Error())
+			yc.logger.Warn("[YagoApiServer] Handle HTTP Request fail", "method", method, "err", err)
 			yc.writeJson(&YagoAPIWrapper{
 				Code: CodeYagoAPIReqReadError,
-				Msg:  "read request body fail",
+				Msg:  yc.message(MsgYagoAPIReqReadError, "read request body fail"),
 			})
 			return
 		}
 		yc.body = bs
 		if err := r.Body.Close(); err != nil {
-			y.logger.Loglnf("[YagoApiServer] Handle HTTP Request fail for [%s], err: %s", method, err.Error())
+			yc.logger.Warn("[YagoApiServer] Handle HTTP Request fail", "method", method, "err", err)
 			yc.writeJson(&YagoAPIWrapper{
 				Code: CodeYagoAPIReqParseError,
-				Msg:  "parse request body fail",
+				Msg:  yc.message(MsgYagoAPIReqParseError, "parse request body fail"),
 			})
 			return
 		}
@@ -105,42 +134,81 @@ func (y *YagoApiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (y *YagoApiServer) invoke(yc *YagoContext) {
 
-	handler, ok := y.handlers[strings.TrimPrefix(yc.serviceName, y.c.Route)]
+	handler, ok := y.handlers[strings.TrimPrefix(yc.serviceName, yc.route)]
 
 	if !ok {
-		y.logger.Loglnf("[YagoApiServer] Handle fail, handler not found for [%s]", yc.serviceName)
+		yc.logger.Debug("[YagoApiServer] Handle fail, handler not found")
 		yc.writeJson(&YagoAPIWrapper{
 			Code: CodeYagoAPIServiceNotFound,
-			Msg:  "service not found",
+			Msg:  yc.message(MsgYagoAPIServiceNotFound, "service not found"),
 		})
 		return
 	}
+	yc.setRequestService()
 
+	decodeSpan := yc.Span().StartChild("decode")
 	param, err := handler.packIn(yc.body)
+	decodeSpan.SetError(err)
+	decodeSpan.End()
 	if err != nil {
+		yc.logger.Debug("[YagoApiServer] Handle fail, req param type not match", "err", err)
 		yc.writeJson(&YagoAPIWrapper{
 			Code: CodeYagoAPIReqParseError,
-			Msg:  "req param type not match",
+			Msg:  yc.message(MsgYagoAPIReqParamError, "req param type not match"),
 		})
 		return
 	}
-	rsp, err := handler.invoke(yc, param)
+	rsp, err := y.traceInvoke(yc, handler, param)
 	if err != nil {
+		if !reportPanicError(y.yago, yc, err) {
+			yc.logger.Warn("[YagoApiServer] Handle fail, invoke error", "err", err)
+		}
 		yc.writeJson(&YagoAPIWrapper{
 			Code: CodeYagoAPIInternalError,
-			Msg:  "invoke error",
+			Msg:  yc.message(MsgYagoAPIInternalError, "invoke error"),
 		})
 		return
 	}
 	yc.writeJson(&YagoAPIWrapper{Data: rsp})
 }
 
+// traceInvoke invokes handler with the invoke span as the span of yc, so spans the
+// handler starts are its children
+func (y *YagoApiServer) traceInvoke(yc *YagoContext, handler *YagoApiHandler, param YagoMessage) (YagoMessage, error) {
+	parent := yc.span
+	yc.span = parent.StartChild("invoke " + yc.serviceName)
+	defer func() {
+		yc.span.End()
+		yc.span = parent
+	}()
+
+	rsp, err := handler.invoke(yc, param)
+	yc.span.SetError(err)
+	return rsp, err
+}
+
 func (y *YagoApiServer) Handler() http.Handler {
 	return y
 }
 
 func (y *YagoApiServer) Pattern() string {
-	return y.c.Route
+	return y.config().Route
+}
+
+func (y *YagoApiServer) bind(yago *Yago) {
+	y.yago = yago
+	y.logger = yago.logger
+}
+
+// RouteNames returns the url patterns of registered services, services are named by
+// their route so versioned servers do not clash, eg: api/v1/user => /api/v1/user
+func (y *YagoApiServer) RouteNames() map[string]string {
+	names := make(map[string]string, len(y.handlers))
+	for serviceName := range y.handlers {
+		pattern := y.config().Route + serviceName
+		names[strings.TrimPrefix(pattern, "/")] = pattern
+	}
+	return names
 }
 
 func (y *YagoApiServer) Register(serviceName string, handler interface{}) error {
@@ -160,7 +228,7 @@ func (y *YagoApiServer) Register(serviceName string, handler interface{}) error
 		return errors.New("invalid handler implementation for yago api handler")
 	}
 	y.handlers[serviceName] = h
-	y.logger.Loglnf("[YagoApiServer] Register service succ for: %s/%s", y.Pattern(), serviceName)
+	y.logger.Info("[YagoApiServer] Register service succ", "route", y.Pattern(), "service", serviceName)
 	return nil
 }
 
@@ -168,7 +236,7 @@ func (y *YagoApiServer) parseQuery(query string) map[string]string {
 	r := make(map[string]string, 0)
 	values, err := url.ParseQuery(query)
 	if err != nil {
-		y.logger.Log("[YagoApiServer] ParseQuery fail for queryString: " + query)
+		y.logger.Debug("[YagoApiServer] ParseQuery fail", "query", query)
 		return r
 	}
 	for k, v := range values {
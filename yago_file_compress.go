@@ -0,0 +1,252 @@
+package yago
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCompressMinSize   = 1024
+	defaultCompressCacheSize = 32 << 20
+)
+
+var defaultCompressTypes = []string{
+	"text/",
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// yagoEncoding is a content coding and the file extension of its precompressed siblings
+type yagoEncoding struct {
+	name string
+	ext  string
+}
+
+// precompressedEncodings are in order of preference
+var precompressedEncodings = []yagoEncoding{{name: "br", ext: ".br"}, {name: "gzip", ext: ".gz"}}
+
+// serveEncoded serves a precompressed sibling or an on the fly gzipped copy of name
+// when the client accepts it, it reports false when name has to be served as is
+func (y *YagoFileServer) serveEncoded(w http.ResponseWriter, r *http.Request, name string) bool {
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return false
+	}
+	info, err := fs.Stat(y.fsys, name)
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	accepted := parseAcceptEncoding(r.Header.Get("Accept-Encoding"))
+	if len(accepted) == 0 {
+		return false
+	}
+	ctype := contentTypeOf(name)
+
+	if y.fsConfig.Precompressed {
+		for _, enc := range precompressedEncodings {
+			if !accepted[enc.name] {
+				continue
+			}
+			bs, modTime, ok := y.readFile(name + enc.ext)
+			if !ok {
+				continue
+			}
+			serveEncodedContent(w, r, name, ctype, enc.name, modTime, bs)
+			return true
+		}
+	}
+
+	if !y.fsConfig.Compress || !accepted["gzip"] || !y.compressible(ctype) {
+		return false
+	}
+	minSize := y.fsConfig.CompressMinSize
+	if minSize <= 0 {
+		minSize = defaultCompressMinSize
+	}
+	if info.Size() < minSize {
+		return false
+	}
+
+	bs, err := y.gzipCached(name, info)
+	if err != nil {
+		return false
+	}
+	serveEncodedContent(w, r, name, ctype, "gzip", info.ModTime(), bs)
+	return true
+}
+
+func (y *YagoFileServer) readFile(name string) ([]byte, time.Time, bool) {
+	info, err := fs.Stat(y.fsys, name)
+	if err != nil || info.IsDir() {
+		return nil, time.Time{}, false
+	}
+	bs, err := fs.ReadFile(y.fsys, name)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	return bs, info.ModTime(), true
+}
+
+func (y *YagoFileServer) compressible(ctype string) bool {
+	types := y.fsConfig.CompressTypes
+	if len(types) == 0 {
+		types = defaultCompressTypes
+	}
+	for _, v := range types {
+		if strings.HasPrefix(ctype, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (y *YagoFileServer) gzipCached(name string, info fs.FileInfo) ([]byte, error) {
+
+	key := name + "@" + strconv.FormatInt(info.ModTime().UnixNano(), 10) + "@" + strconv.FormatInt(info.Size(), 10)
+	if bs, ok := y.compressed.get(key); ok {
+		return bs, nil
+	}
+
+	f, err := y.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := io.Copy(zw, f); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	y.compressed.put(key, buf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// serveEncodedContent serves encoded bytes of name, Range requests apply to the encoded bytes
+func serveEncodedContent(w http.ResponseWriter, r *http.Request, name, ctype, encoding string, modTime time.Time, bs []byte) {
+	h := w.Header()
+	h.Set("Content-Type", ctype)
+	h.Set("Content-Encoding", encoding)
+	// the encoded representation differs from the identity one, so does its etag
+	if etag := h.Get("ETag"); strings.HasSuffix(etag, `"`) {
+		h.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+encoding+`"`)
+	}
+	http.ServeContent(w, r, name, modTime, bytes.NewReader(bs))
+}
+
+func contentTypeOf(name string) string {
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		return ctype
+	}
+	return "application/octet-stream"
+}
+
+// parseAcceptEncoding returns codings accepted by an Accept-Encoding header
+func parseAcceptEncoding(header string) map[string]bool {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if coding == "*" {
+			for _, enc := range precompressedEncodings {
+				if _, ok := accepted[enc.name]; !ok {
+					accepted[enc.name] = q > 0
+				}
+			}
+			continue
+		}
+		accepted[coding] = q > 0
+	}
+	for k, v := range accepted {
+		if !v {
+			delete(accepted, k)
+		}
+	}
+	return accepted
+}
+
+// yagoCompressCache is a lru cache of compressed files bounded by total bytes
+type yagoCompressCache struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	order   *list.List
+	items   map[string]*list.Element
+}
+
+type yagoCompressEntry struct {
+	key string
+	bs  []byte
+}
+
+func newYagoCompressCache(maxSize int64) *yagoCompressCache {
+	if maxSize <= 0 {
+		maxSize = defaultCompressCacheSize
+	}
+	return &yagoCompressCache{maxSize: maxSize, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *yagoCompressCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*yagoCompressEntry).bs, true
+}
+
+func (c *yagoCompressCache) put(key string, bs []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if int64(len(bs)) > c.maxSize {
+		return
+	}
+	if _, ok := c.items[key]; ok {
+		return
+	}
+	c.items[key] = c.order.PushFront(&yagoCompressEntry{key: key, bs: bs})
+	c.size += int64(len(bs))
+	for c.size > c.maxSize {
+		e := c.order.Back()
+		entry := e.Value.(*yagoCompressEntry)
+		c.order.Remove(e)
+		delete(c.items, entry.key)
+		c.size -= int64(len(entry.bs))
+	}
+}
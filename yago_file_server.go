@@ -1,38 +1,310 @@
 package yago
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"io"
+	"io/fs"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultFileMaxAge = 60
+
+	// fingerprintLength is the count of hex chars of the content hash in fingerprinted names
+	fingerprintLength = 6
 )
 
 // YagoFileServerConfig
 // serve static file server support for yago server
 type YagoFileServerConfig struct {
+	// Dir is the local directory to serve, when FS is set Dir is the sub directory inside FS
 	Dir   string `json:"dir"`
 	Route string `json:"route"`
+
+	// FS serves files instead of the OS directory, eg: an embed.FS
+	FS fs.FS `json:"-"`
+
+	// Fingerprint serves every file under a content hashed name too, eg: base.3f9a1c.js,
+	// which is cached by browsers forever, the manifest is built once on startup
+	Fingerprint bool `json:"fingerprint"`
+
+	// MaxAge is the Cache-Control max-age in seconds of files not requested by
+	// fingerprinted names, default 60
+	MaxAge int `json:"maxAge"`
+
+	// Precompressed serves the .br or .gz sibling of a file when it exists and the client accepts it
+	Precompressed bool `json:"precompressed"`
+
+	// Compress gzips files of CompressTypes on the fly and caches them in memory
+	Compress bool `json:"compress"`
+
+	// CompressTypes are content type prefixes compressed on the fly, default text and
+	// javascript, json, xml, svg
+	CompressTypes []string `json:"compressTypes"`
+
+	// CompressMinSize is the size in bytes files need to be compressed on the fly, default 1024
+	CompressMinSize int64 `json:"compressMinSize"`
+
+	// CompressCacheSize bounds the bytes of cached compressed files, default 32MB
+	CompressCacheSize int64 `json:"compressCacheSize"`
+
+	// DisableListing responds 404 for directories without index files instead of listing them
+	DisableListing bool `json:"disableListing"`
+
+	// IndexFiles are served for directories in order, default index.html
+	IndexFiles []string `json:"indexFiles"`
+
+	// SPAFallback is served for unknown paths without file extension, eg: index.html
+	// of a single page app handling its routes in the browser
+	SPAFallback string `json:"spaFallback"`
+
+	// NotFoundFile is served with status 404 for unknown paths
+	NotFoundFile string `json:"notFoundFile"`
+
+	// NotFound handles unknown paths instead of NotFoundFile
+	NotFound http.Handler `json:"-"`
 }
 
 type YagoFileServer struct {
 	fsConfig  *YagoFileServerConfig
 	fsPath    string
+	fsys      fs.FS
 	fsHandler http.Handler
+
+	// manifest maps file paths to fingerprinted ones, fingerprints maps them back
+	manifest     map[string]string
+	fingerprints map[string]string
+	etags        map[string]string
+
+	compressed *yagoCompressCache
 }
 
 func NewYagoFileServer(fsConfig *YagoFileServerConfig) (*YagoFileServer, error) {
-	if fsConfig == nil || fsConfig.Dir == "" || fsConfig.Route == "" {
+	if fsConfig == nil || (fsConfig.Dir == "" && fsConfig.FS == nil) || fsConfig.Route == "" {
 		return nil, errors.New("empty fsConfig is not allowed")
 	}
+	fsys, err := subFS(fsConfig.FS, fsConfig.Dir)
+	if err != nil {
+		return nil, err
+	}
 	fsPath := "/" + fsConfig.Route + "/"
-	fsHandler := http.StripPrefix(fsPath, http.FileServer(http.Dir("./"+fsConfig.Dir)))
-	return &YagoFileServer{
-		fsConfig:  fsConfig,
-		fsHandler: fsHandler,
-		fsPath:    fsPath,
-	}, nil
+	fsHandler := http.StripPrefix(fsPath, http.FileServer(http.FS(fsys)))
+	y := &YagoFileServer{
+		fsConfig:     fsConfig,
+		fsHandler:    fsHandler,
+		fsPath:       fsPath,
+		fsys:         fsys,
+		manifest:     make(map[string]string),
+		fingerprints: make(map[string]string),
+		etags:        make(map[string]string),
+		compressed:   newYagoCompressCache(fsConfig.CompressCacheSize),
+	}
+	if fsConfig.Fingerprint {
+		if err := y.buildManifest(); err != nil {
+			return nil, err
+		}
+	}
+	return y, nil
 }
 
 func (y *YagoFileServer) Handler() http.Handler {
-	return y.fsHandler
+	return y
+}
+
+func (y *YagoFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	name := strings.TrimPrefix(r.URL.Path, y.fsPath)
+
+	// cacheControl is only set once the file is resolved, misses must never be cached,
+	// the content hash etag only holds for fingerprinted names as the manifest is built once,
+	// plain names are revalidated by Last-Modified
+	var cacheControl, etag string
+	if orig, ok := y.fingerprints[name]; ok {
+		cacheControl = "public, max-age=31536000, immutable"
+		etag = y.etags[orig]
+		name = orig
+	} else {
+		maxAge := y.fsConfig.MaxAge
+		if maxAge <= 0 {
+			maxAge = defaultFileMaxAge
+		}
+		cacheControl = "public, max-age=" + strconv.Itoa(maxAge)
+	}
+
+	if y.fsConfig.Precompressed || y.fsConfig.Compress {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(y.fsys, name)
+	switch {
+	case err != nil:
+		if y.fsConfig.SPAFallback != "" && path.Ext(name) == "" {
+			// the fallback answers any client route, it is revalidated on every request
+			w.Header().Set("Cache-Control", "no-cache")
+			y.serveFile(w, r, y.fsConfig.SPAFallback)
+			return
+		}
+		y.notFound(w, r)
+	case !info.IsDir():
+		w.Header().Set("Cache-Control", cacheControl)
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		y.serveFile(w, r, name)
+	default:
+		y.serveDir(w, r, name, cacheControl)
+	}
+}
+
+func (y *YagoFileServer) serveDir(w http.ResponseWriter, r *http.Request, name, cacheControl string) {
+
+	indexFiles := y.fsConfig.IndexFiles
+	if len(indexFiles) == 0 {
+		indexFiles = []string{"index.html"}
+	}
+
+	for _, v := range indexFiles {
+		index := path.Join(name, v)
+		if info, err := fs.Stat(y.fsys, index); err != nil || info.IsDir() {
+			continue
+		}
+		// relative links of the index file resolve against the directory only with a trailing slash
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			target := path.Base(r.URL.Path) + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+		y.serveFile(w, r, index)
+		return
+	}
+
+	if y.fsConfig.DisableListing {
+		y.notFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	y.fsHandler.ServeHTTP(w, r)
+}
+
+func (y *YagoFileServer) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+
+	if (y.fsConfig.Precompressed || y.fsConfig.Compress) && y.serveEncoded(w, r, name) {
+		return
+	}
+
+	f, err := y.fsys.Open(name)
+	if err != nil {
+		y.notFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		y.notFound(w, r)
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		bs, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(bs)
+	}
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
+
+func (y *YagoFileServer) notFound(w http.ResponseWriter, r *http.Request) {
+
+	// a handler resolving the file may have set cache headers already
+	w.Header().Del("ETag")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if y.fsConfig.NotFound != nil {
+		y.fsConfig.NotFound.ServeHTTP(w, r)
+		return
+	}
+
+	if y.fsConfig.NotFoundFile != "" {
+		if bs, err := fs.ReadFile(y.fsys, y.fsConfig.NotFoundFile); err == nil {
+			w.Header().Set("Content-Type", contentTypeOf(y.fsConfig.NotFoundFile))
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(bs)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+// Manifest returns file paths mapped to their fingerprinted paths
+func (y *YagoFileServer) Manifest() map[string]string {
+	m := make(map[string]string, len(y.manifest))
+	for k, v := range y.manifest {
+		m[k] = v
+	}
+	return m
+}
+
+// Asset returns the url of a file, fingerprinted when Fingerprint is enabled, eg: css/base.css
+func (y *YagoFileServer) Asset(p string) string {
+	p = strings.TrimPrefix(p, "/")
+	if hashed, ok := y.manifest[p]; ok {
+		return y.fsPath + hashed
+	}
+	return y.fsPath + p
+}
+
+// resolveAsset fingerprints an url path below the file server
+func (y *YagoFileServer) resolveAsset(p string) (string, bool) {
+	if !strings.HasPrefix(p, y.fsPath) {
+		return "", false
+	}
+	return y.Asset(strings.TrimPrefix(p, y.fsPath)), true
+}
+
+func (y *YagoFileServer) buildManifest() error {
+	return fs.WalkDir(y.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := y.fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		sum := hex.EncodeToString(h.Sum(nil))
+
+		ext := path.Ext(p)
+		hashed := strings.TrimSuffix(p, ext) + "." + sum[:fingerprintLength] + ext
+		y.manifest[p] = hashed
+		y.fingerprints[hashed] = p
+		y.etags[p] = `"` + sum[:2*fingerprintLength] + `"`
+		return nil
+	})
 }
 
 func (y *YagoFileServer) Pattern() string {
@@ -42,3 +314,9 @@ func (y *YagoFileServer) Pattern() string {
 func (y *YagoFileServer) Type() string {
 	return "YagoFileServer"
 }
+
+// RouteNames names the file server by its Route, the file path is the param path,
+// eg: {{url "static" "path" "css/base.css"}}
+func (y *YagoFileServer) RouteNames() map[string]string {
+	return map[string]string{y.fsConfig.Route: y.fsPath + "{path...}"}
+}
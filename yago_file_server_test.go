@@ -0,0 +1,178 @@
+package yago
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoFileServerFingerprint(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"js/base.js":  {Data: []byte(`console.log("yago")`)},
+		"page.layout": {Data: []byte(`{{asset "js/base.js"}}`)},
+	}
+
+	fServer, err := NewYagoFileServer(&YagoFileServerConfig{FS: fsys, Route: "static", Fingerprint: true, MaxAge: 30})
+	assert.Nil(t, err)
+
+	hashed := fServer.Manifest()["js/base.js"]
+	assert.Regexp(t, `^js/base\.[0-9a-f]{6}\.js$`, hashed)
+	assert.Equal(t, "/static/"+hashed, fServer.Asset("js/base.js"))
+
+	tServer := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/p",
+		AssetPrefix: "/static",
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "page", Templates: []string{"page.layout"}}},
+	}, fsys)
+	assert.Nil(t, tServer.Register("page", func(ctx *YagoContext) (interface{}, error) { return nil, nil }))
+
+	y, err := New(WithConfig(&YagoConfig{Port: 8080}), WithFileServer(fServer), WithTemplateServer(tServer))
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	y.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/p/page", nil))
+	assert.Equal(t, "/static/"+hashed, w.Body.String())
+
+	var uts = []struct {
+		Path         string
+		Status       int
+		CacheControl string
+	}{
+		{Path: "/static/" + hashed, Status: http.StatusOK, CacheControl: "public, max-age=31536000, immutable"},
+		{Path: "/static/js/base.js", Status: http.StatusOK, CacheControl: "public, max-age=30"},
+		{Path: "/static/js/base.000000.js", Status: http.StatusNotFound, CacheControl: "no-cache"},
+	}
+	for _, uc := range uts {
+		w := httptest.NewRecorder()
+		y.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uc.Path, nil))
+		assert.Equal(t, uc.Status, w.Code, uc.Path)
+		assert.Equal(t, uc.CacheControl, w.Header().Get("Cache-Control"), uc.Path)
+		if uc.Status == http.StatusOK {
+			assert.Equal(t, `console.log("yago")`, w.Body.String())
+		}
+	}
+
+	etag := fServer.etags["js/base.js"]
+	r := httptest.NewRequest(http.MethodGet, "/static/"+hashed, nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	y.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// plain names never get the startup hash, a file changed since is served again
+	fsys["js/base.js"] = &fstest.MapFile{Data: []byte(`console.log("changed")`)}
+	r = httptest.NewRequest(http.MethodGet, "/static/js/base.js", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	y.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.Equal(t, `console.log("changed")`, w.Body.String())
+}
+
+func TestYagoFileServerCompress(t *testing.T) {
+
+	js := bytes.Repeat([]byte(`console.log("yago");`), 100)
+	fsys := fstest.MapFS{
+		"css/base.css":    {Data: []byte(`body{}`)},
+		"css/base.css.br": {Data: []byte(`brotli`)},
+		"css/base.css.gz": {Data: []byte(`gzipped`)},
+		"js/base.js":      {Data: js},
+		"img/a.png":       {Data: bytes.Repeat([]byte{1}, 2048)},
+	}
+
+	fServer, err := NewYagoFileServer(&YagoFileServerConfig{FS: fsys, Route: "static", Precompressed: true, Compress: true})
+	assert.Nil(t, err)
+
+	var uts = []struct {
+		Path     string
+		Accept   string
+		Range    string
+		Encoding string
+		Body     string
+	}{
+		{Path: "/static/css/base.css", Accept: "gzip, br", Encoding: "br", Body: "brotli"},
+		{Path: "/static/css/base.css", Accept: "gzip, br;q=0", Encoding: "gzip", Body: "gzipped"},
+		{Path: "/static/css/base.css", Accept: "", Encoding: "", Body: "body{}"},
+		{Path: "/static/css/base.css", Accept: "gzip", Range: "bytes=0-1", Encoding: "gzip", Body: "gz"},
+		{Path: "/static/img/a.png", Accept: "gzip", Encoding: ""},
+		{Path: "/static/js/base.js", Accept: "gzip", Encoding: "gzip"},
+	}
+	for _, uc := range uts {
+		r := httptest.NewRequest(http.MethodGet, uc.Path, nil)
+		r.Header.Set("Accept-Encoding", uc.Accept)
+		if uc.Range != "" {
+			r.Header.Set("Range", uc.Range)
+		}
+		w := httptest.NewRecorder()
+		fServer.ServeHTTP(w, r)
+		assert.Equal(t, uc.Encoding, w.Header().Get("Content-Encoding"), uc.Path)
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"), uc.Path)
+		if uc.Body != "" {
+			assert.Equal(t, uc.Body, w.Body.String(), uc.Path)
+		}
+		if uc.Range != "" {
+			assert.Equal(t, http.StatusPartialContent, w.Code, uc.Path)
+		}
+		if uc.Path == "/static/js/base.js" {
+			assert.Equal(t, "text/javascript; charset=utf-8", w.Header().Get("Content-Type"))
+			zr, err := gzip.NewReader(w.Body)
+			assert.Nil(t, err)
+			bs, _ := io.ReadAll(zr)
+			assert.Equal(t, js, bs)
+		}
+	}
+}
+
+func TestYagoFileServerFallback(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"index.html":        {Data: []byte(`app`)},
+		"404.html":          {Data: []byte(`missing`)},
+		"docs/index.htm":    {Data: []byte(`docs`)},
+		"assets/js/base.js": {Data: []byte(`js`)},
+	}
+
+	var uts = []struct {
+		Config       *YagoFileServerConfig
+		Path         string
+		Status       int
+		Body         string
+		CacheControl string
+		Location     string
+	}{
+		{Config: &YagoFileServerConfig{}, Path: "/app/assets/", Status: http.StatusOK, Body: "js/", CacheControl: "public, max-age=60"},
+		{Config: &YagoFileServerConfig{DisableListing: true}, Path: "/app/assets/", Status: http.StatusNotFound, CacheControl: "no-cache"},
+		{Config: &YagoFileServerConfig{}, Path: "/app/", Status: http.StatusOK, Body: "app", CacheControl: "public, max-age=60"},
+		{Config: &YagoFileServerConfig{IndexFiles: []string{"index.htm"}}, Path: "/app/docs/", Status: http.StatusOK, Body: "docs", CacheControl: "public, max-age=60"},
+		{Config: &YagoFileServerConfig{IndexFiles: []string{"index.htm"}}, Path: "/app/docs", Status: http.StatusMovedPermanently, Location: "/app/docs/"},
+		{Config: &YagoFileServerConfig{IndexFiles: []string{"index.htm"}}, Path: "/app/docs?page=2", Status: http.StatusMovedPermanently, Location: "/app/docs/?page=2"},
+		{Config: &YagoFileServerConfig{SPAFallback: "index.html"}, Path: "/app/todo/1", Status: http.StatusOK, Body: "app", CacheControl: "no-cache"},
+		{Config: &YagoFileServerConfig{SPAFallback: "index.html"}, Path: "/app/assets/js/none.js", Status: http.StatusNotFound, CacheControl: "no-cache"},
+		{Config: &YagoFileServerConfig{NotFoundFile: "404.html"}, Path: "/app/none", Status: http.StatusNotFound, Body: "missing", CacheControl: "no-cache"},
+		{Config: &YagoFileServerConfig{NotFound: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		})}, Path: "/app/none", Status: http.StatusGone, CacheControl: "no-cache"},
+	}
+	for _, uc := range uts {
+		uc.Config.FS, uc.Config.Route = fsys, "app"
+		fServer, err := NewYagoFileServer(uc.Config)
+		assert.Nil(t, err)
+
+		w := httptest.NewRecorder()
+		fServer.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uc.Path, nil))
+		assert.Equal(t, uc.Status, w.Code, uc.Path)
+		assert.Equal(t, uc.CacheControl, w.Header().Get("Cache-Control"), uc.Path)
+		assert.Equal(t, uc.Location, w.Header().Get("Location"), uc.Path)
+		if uc.Body != "" {
+			assert.Contains(t, w.Body.String(), uc.Body, uc.Path)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package yago
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+)
+
+// YagoHTTPError carries the http status a template handler wants to respond with
+type YagoHTTPError struct {
+	Status int
+	Err    error
+}
+
+func NewYagoHTTPError(status int, err error) *YagoHTTPError {
+	return &YagoHTTPError{Status: status, Err: err}
+}
+
+func (y *YagoHTTPError) Error() string {
+	if y.Err == nil {
+		return http.StatusText(y.Status)
+	}
+	return y.Err.Error()
+}
+
+func (y *YagoHTTPError) Unwrap() error {
+	return y.Err
+}
+
+// StatusOfError returns the status of a YagoHTTPError in the chain of err,
+// or http.StatusInternalServerError for any other error
+func StatusOfError(err error) int {
+	var he *YagoHTTPError
+	if errors.As(err, &he) && he.Status > 0 {
+		return he.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// YagoErrorPage is the data of error page templates
+type YagoErrorPage struct {
+	Status      int
+	StatusText  string
+	Error       string
+	Method      string
+	Path        string
+	Query       map[string]string
+	ServiceName string
+
+	// Templates and DefinedTemplates are only filled in DevMode, DefinedTemplates lists
+	// the template names defined by the render of the page
+	Templates        []string
+	DefinedTemplates string
+}
+
+const devErrorLayout = `<!DOCTYPE html>
+<html>
+<head><title>{{.Status}} {{.StatusText}}</title></head>
+<body style="font-family:monospace">
+<h1>{{.Status}} {{.StatusText}}</h1>
+<p>{{.Method}} {{.Path}}{{if .ServiceName}} (service: {{.ServiceName}}){{end}}</p>
+<h2>Error</h2>
+<pre>{{.Error}}</pre>
+{{if .Query}}<h2>Query</h2>
+<pre>{{range $k, $v := .Query}}{{$k}} = {{$v}}
+{{end}}</pre>{{end}}
+{{if .Templates}}<h2>Templates</h2>
+<pre>{{range .Templates}}{{.}}
+{{end}}</pre>{{end}}
+{{if .DefinedTemplates}}<h2>Defined Templates</h2>
+<pre>{{.DefinedTemplates}}</pre>{{end}}
+</body>
+</html>`
+
+var devErrorTemplate = template.Must(template.New("yago-dev-error").Parse(devErrorLayout))
+
+func errorLayoutKey(status int) string {
+	// keys of error layouts can never be produced by a request path
+	return fmt.Sprintf("!error/%d", status)
+}
+
+func (y *YagoTemplateServer) newErrorPage(ctx *YagoContext, status int, err error) *YagoErrorPage {
+	page := &YagoErrorPage{
+		Status:      status,
+		StatusText:  http.StatusText(status),
+		Method:      ctx.r.Method,
+		Path:        ctx.path,
+		Query:       ctx.query,
+		ServiceName: ctx.serviceName,
+	}
+	if err != nil {
+		page.Error = err.Error()
+	}
+	if !y.config().DevMode {
+		return page
+	}
+
+	y.mu.RLock()
+	page.Templates = y.tmpls[ctx.serviceName]
+	render := y.renders[ctx.serviceName]
+	y.mu.RUnlock()
+
+	if render != nil {
+		page.DefinedTemplates = render.t.DefinedTemplates()
+	}
+	return page
+}
+
+// renderError responds status with the error layout bound for it,
+// DevMode always uses the builtin detailed error page
+func (y *YagoTemplateServer) renderError(ctx *YagoContext, status int, err error) {
+
+	page := y.newErrorPage(ctx, status, err)
+
+	if y.config().DevMode {
+		buf := renderBufferPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer putRenderBuffer(buf)
+		if err := devErrorTemplate.Execute(buf, page); err != nil {
+			ctx.Logger().Error("[YagoTemplateServer] Render dev error page fail", "path", ctx.path, "err", err)
+			http.Error(ctx.w, page.StatusText, status)
+			return
+		}
+		writeHTML(ctx, status, buf.Bytes())
+		return
+	}
+
+	render := y.errorRender(status)
+	if render == nil {
+		http.Error(ctx.w, page.StatusText, status)
+		return
+	}
+
+	if err := render.RenderStatus(ctx, status, page); err != nil {
+		ctx.Logger().Error("[YagoTemplateServer] Render error page fail", "path", ctx.path, "err", err)
+		if !render.stream {
+			http.Error(ctx.w, page.StatusText, status)
+		}
+	}
+}
+
+func (y *YagoTemplateServer) errorRender(status int) *YagoRender {
+
+	var layout, fallback *ErrorLayoutConfig
+	for _, v := range y.config().ErrorLayouts {
+		if v.Status == status {
+			layout = v
+		}
+		if v.Status == 0 {
+			fallback = v
+		}
+	}
+	if layout == nil {
+		layout = fallback
+	}
+	if layout == nil || len(layout.Templates) == 0 {
+		return nil
+	}
+
+	key := errorLayoutKey(layout.Status)
+
+	y.mu.RLock()
+	render, ok := y.renders[key]
+	renderErr := y.renderErrs[key]
+	y.mu.RUnlock()
+	if ok || renderErr != nil {
+		return render
+	}
+
+	tmpls := append(append([]string{}, layout.Templates...), y.config().BaseLayouts...)
+	render, err := y.newRender(key, tmpls)
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.tmpls[key] = tmpls
+	if err != nil {
+		y.logger.Error("[YagoTemplateServer] Parse error layout fail", "status", layout.Status, "err", err)
+		y.renderErrs[key] = err
+		return nil
+	}
+	y.renders[key] = render
+	return render
+}
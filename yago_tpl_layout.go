@@ -0,0 +1,157 @@
+package yago
+
+import (
+	"fmt"
+	"html/template"
+	"path"
+	"sort"
+	"text/template/parse"
+)
+
+// LayoutConfig
+// LayoutConfig is a named layout, the root layout has no Extends and its first
+// template is the entry of the page, it declares overridable blocks with {{block}}.
+// A nested layout extends another layout and may override its blocks or declare new ones
+type LayoutConfig struct {
+	Name      string   `json:"name"`
+	Extends   string   `json:"extends"`
+	Templates []string `json:"templates"`
+}
+
+// layoutChain returns the layouts from the root layout down to the named one
+func (y *YagoTemplateServer) layoutChain(name string) ([]*LayoutConfig, error) {
+
+	layouts := make(map[string]*LayoutConfig)
+	for _, v := range y.config().Layouts {
+		layouts[v.Name] = v
+	}
+
+	chain := []*LayoutConfig{}
+	seen := make(map[string]bool)
+	for child := ""; name != ""; child, name = name, layouts[name].Extends {
+		l, ok := layouts[name]
+		if !ok {
+			if child == "" {
+				return nil, fmt.Errorf("undefined layout %q", name)
+			}
+			return nil, fmt.Errorf("layout %q extends undefined layout %q", child, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("layout %q extends itself", name)
+		}
+		seen[name] = true
+		chain = append([]*LayoutConfig{l}, chain...)
+	}
+	if len(chain[0].Templates) == 0 {
+		return nil, fmt.Errorf("root layout %q has no templates", chain[0].Name)
+	}
+	return chain, nil
+}
+
+// getLayoutTemplates returns the templates of a layout chain: root layout, BaseLayouts, nested layouts
+func (y *YagoTemplateServer) getLayoutTemplates(name string) ([]string, error) {
+	chain, err := y.layoutChain(name)
+	if err != nil {
+		return nil, err
+	}
+	t := append([]string{}, chain[0].Templates...)
+	t = append(t, y.config().BaseLayouts...)
+	for _, l := range chain[1:] {
+		t = append(t, l.Templates...)
+	}
+	return t, nil
+}
+
+// checkLayoutBlocks reports blocks referenced by the page but defined nowhere,
+// and blocks defined by the page which its layout never declares nor uses
+func (y *YagoTemplateServer) checkLayoutBlocks(page *PageLayoutConfig, render *YagoRender) error {
+
+	referenced := make(map[string]bool)
+	for _, t := range render.t.Templates() {
+		if t.Tree != nil {
+			collectTemplateRefs(t.Tree.Root, referenced)
+		}
+	}
+	for _, name := range sortedKeys(referenced) {
+		if render.t.Lookup(name) == nil {
+			return fmt.Errorf("page %q references undefined block %q", page.ServiceName, name)
+		}
+	}
+
+	layoutTmpls, err := y.getLayoutTemplates(page.Layout)
+	if err != nil {
+		return err
+	}
+	declared, err := y.definedTemplates(layoutTmpls)
+	if err != nil {
+		return err
+	}
+	defined, err := y.definedTemplates(page.Templates)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(defined) {
+		if !declared[name] && !referenced[name] {
+			return fmt.Errorf("page %q defines block %q which layout %q does not declare", page.ServiceName, name, page.Layout)
+		}
+	}
+	return nil
+}
+
+// definedTemplates returns names defined in tmpls, file names are excluded
+func (y *YagoTemplateServer) definedTemplates(tmpls []string) (map[string]bool, error) {
+
+	names := make(map[string]bool)
+	if len(tmpls) == 0 {
+		return names, nil
+	}
+
+	t, err := template.New("").Funcs(y.bindFuncs).ParseFS(y.layouts(), tmpls...)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]bool)
+	for _, v := range tmpls {
+		files[path.Base(v)] = true
+	}
+	for _, v := range t.Templates() {
+		if v.Name() == "" || files[v.Name()] {
+			continue
+		}
+		names[v.Name()] = true
+	}
+	return names, nil
+}
+
+func collectTemplateRefs(node parse.Node, refs map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, v := range n.Nodes {
+			collectTemplateRefs(v, refs)
+		}
+	case *parse.TemplateNode:
+		refs[n.Name] = true
+	case *parse.IfNode:
+		collectTemplateRefs(n.List, refs)
+		collectTemplateRefs(n.ElseList, refs)
+	case *parse.RangeNode:
+		collectTemplateRefs(n.List, refs)
+		collectTemplateRefs(n.ElseList, refs)
+	case *parse.WithNode:
+		collectTemplateRefs(n.List, refs)
+		collectTemplateRefs(n.ElseList, refs)
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
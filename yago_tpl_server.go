@@ -3,9 +3,12 @@ package yago
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io/fs"
 	"net/http"
 	"net/url"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,6 +17,25 @@ type YaogoTemplateHandler func(ctx *YagoContext) (data interface{}, err error)
 type PageLayoutConfig struct {
 	ServiceName string   `json:"name"`
 	Templates   []string `json:"templates"`
+
+	// Path is the url pattern of the page below Route, eg: / for the index page,
+	// /todo/{id} for a param or /docs/{path...} for the rest of the path,
+	// default is /{ServiceName}
+	Path string `json:"path"`
+
+	// Layout is the name of the layout the page extends, the page overrides
+	// blocks of the layout with {{define}}, see LayoutConfig
+	Layout string `json:"layout"`
+
+	// Stream renders the page without buffering, use it only for very large pages
+	Stream bool `json:"stream"`
+}
+
+// ErrorLayoutConfig
+// ErrorLayoutConfig binds error page templates for a http status
+type ErrorLayoutConfig struct {
+	Status    int      `json:"status"`
+	Templates []string `json:"templates"`
 }
 
 // YagoTemplateConfig
@@ -23,48 +45,170 @@ type YagoTemplateConfig struct {
 	// Route is pattern prefix to serve for http server
 	Route string `json:"route"`
 
-	// LayoutDir is the local path that all template files defined
+	// LayoutDir is the local path that all template files defined,
+	// when FS is set LayoutDir is the sub directory inside FS
 	LayoutDir string `json:"layoutDir"`
 
+	// FS serves template files instead of the OS directory, eg: an embed.FS
+	FS fs.FS `json:"-"`
+
 	// BaseLayouts will be rendered everytime
 	BaseLayouts []string `json:"baseLayouts"`
 
 	// PageLayouts
 	PageLayouts []*PageLayoutConfig `json:"pageLayouts"`
 
+	// Layouts are named layouts that pages and other layouts extend
+	Layouts []*LayoutConfig `json:"layouts"`
+
 	// Timeout
 	Timeout int `json:"timeout"`
+
+	// DevMode watches LayoutDir and re-parses changed templates without restart,
+	// template parse errors are shown in browser instead of failing Register
+	DevMode bool `json:"devMode"`
+
+	// ReloadInterval is the polling interval in milliseconds used by DevMode, default 1000
+	ReloadInterval int `json:"reloadInterval"`
+
+	// FragmentHeader names the request header asking for a single template of the page,
+	// eg: HTMX requests with hx-headers, default X-Yago-Fragment
+	FragmentHeader string `json:"fragmentHeader"`
+
+	// FragmentQuery names the query param asking for a single template of the page, default _fragment
+	FragmentQuery string `json:"fragmentQuery"`
+
+	// AssetPrefix is the url prefix of static assets used by the asset template func, eg: /static
+	AssetPrefix string `json:"assetPrefix"`
+
+	// ErrorLayouts are rendered together with BaseLayouts when a page fails,
+	// the layout with Status 0 is used for every status without its own layout
+	ErrorLayouts []*ErrorLayoutConfig `json:"errorLayouts"`
 }
 
 type YagoTemplateServer struct {
-	hds       map[string]YaogoTemplateHandler
-	renders   map[string]*YagoRender
-	c         *YagoTemplateConfig
+	hds        map[string]YaogoTemplateHandler
+	renders    map[string]*YagoRender
+	renderErrs map[string]error
+	tmpls      map[string][]string
+	router     *yagoRouter
+	layoutFS   fs.FS
+	yago       *Yago
+	// c is swapped on reload together with the renders, see applyConfig
+	c         atomic.Pointer[YagoTemplateConfig]
 	logger    Logger
 	bindFuncs map[string]interface{}
+	mu        sync.RWMutex
 }
 
 func NewYagoTemplateServer(c *YagoTemplateConfig) (*YagoTemplateServer, error) {
+	if c == nil {
+		return nil, errors.New("empty template config is not allowed")
+	}
+
+	layoutFS, err := subFS(c.FS, c.LayoutDir)
+	if err != nil {
+		return nil, err
+	}
+
 	yServer := &YagoTemplateServer{
-		c:         c,
-		bindFuncs: make(map[string]interface{}),
-		logger:    &DefaultLogger{},
-		hds:       make(map[string]YaogoTemplateHandler),
-		renders:   make(map[string]*YagoRender),
+		bindFuncs:  DefaultFuncs(),
+		logger:     &DefaultLogger{},
+		hds:        make(map[string]YaogoTemplateHandler),
+		renders:    make(map[string]*YagoRender),
+		renderErrs: make(map[string]error),
+		tmpls:      make(map[string][]string),
+		router:     &yagoRouter{},
+		layoutFS:   layoutFS,
+	}
+	yServer.c.Store(c)
+
+	yServer.bindFuncs["url"] = yServer.funcURL
+	yServer.bindFuncs["asset"] = yServer.funcAsset
+	// T is bound to the locale of each request on render, see YagoRender
+	yServer.bindFuncs["T"] = func(key string, args ...interface{}) string {
+		return (*YagoI18n)(nil).translate("", key, key, args...)
 	}
 
 	return yServer, nil
 }
 
-// BindFuncs
+func (y *YagoTemplateServer) config() *YagoTemplateConfig {
+	return y.c.Load()
+}
+
+func (y *YagoTemplateServer) layouts() fs.FS {
+	y.mu.RLock()
+	defer y.mu.RUnlock()
+	return y.layoutFS
+}
+
+// BindFuncs adds funcs to templates parsed afterwards, funcs with the same name
+// as a builtin one of DefaultFuncs replace it
 func (y *YagoTemplateServer) BindFuncs(bindFuncs map[string]interface{}) {
-	y.bindFuncs = bindFuncs
+	for k, v := range bindFuncs {
+		y.bindFuncs[k] = v
+	}
+}
+
+// funcURL builds the url of a named route, see Yago.URL, pages of this server are
+// the only routes known before it is mounted on Yago,
+// eg: {{url "item" "id" .Id "tab" "detail"}} builds /app/todo/1?tab=detail
+func (y *YagoTemplateServer) funcURL(name string, kvs ...interface{}) (string, error) {
+	if y.yago != nil {
+		return y.yago.URL(name, kvs...)
+	}
+	params, err := pairsToParams(kvs)
+	if err != nil {
+		return "", err
+	}
+	y.mu.RLock()
+	route := y.router.find(name)
+	y.mu.RUnlock()
+	if route == nil {
+		return "", errors.New("url of undefined page: " + name)
+	}
+	return route.build(params)
+}
+
+func (y *YagoTemplateServer) bind(yago *Yago) {
+	y.yago = yago
+	y.logger = yago.logger
+}
+
+func (y *YagoTemplateServer) i18n() *YagoI18n {
+	if y.yago == nil {
+		return nil
+	}
+	return y.yago.i18n
+}
+
+// RouteNames returns the url patterns of registered pages by service name
+func (y *YagoTemplateServer) RouteNames() map[string]string {
+	y.mu.RLock()
+	defer y.mu.RUnlock()
+	names := make(map[string]string, len(y.router.routes))
+	for _, r := range y.router.routes {
+		names[r.name] = r.pattern
+	}
+	return names
+}
+
+// funcAsset returns the url of a static asset below AssetPrefix, fingerprinted by
+// the file server serving it once the server is mounted on Yago, see Yago.Asset
+func (y *YagoTemplateServer) funcAsset(p string) string {
+	u := joinRoute(y.config().AssetPrefix, p)
+	if y.yago != nil {
+		return y.yago.Asset(u)
+	}
+	return u
 }
 
 // ServeHTTP
 func (y *YagoTemplateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(y.c.Timeout))
+	c := y.config()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(c.Timeout))
 	defer cancel()
 
 	queryString, _ := url.QueryUnescape(r.URL.RawQuery)
@@ -80,19 +224,29 @@ func (y *YagoTemplateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	yc.r = r
 	yc.Context = ctx
 
-	fs := strings.Split(p, "/")
-	if len(fs) >= 3 {
-		yc.route = fs[1]
-		yc.serviceName = fs[2]
+	yc.setLocale(y.i18n())
+	yc.route = c.Route
+	if route, params := y.matchRoute(p); route != nil {
+		yc.serviceName = route.name
+		yc.params = params
+		yc.setRequestService()
 	}
 
-	y.logger.Loglnf("[YagoTemplateServer] Handle HTTP Request for [%s] %s, service: %s", method, p, yc.serviceName)
+	yc.fragment = r.Header.Get(y.fragmentHeader())
+	if yc.fragment == "" {
+		yc.fragment = yc.Query(y.fragmentQuery())
+	}
+	// the same url responds a full page or a fragment depending on the header
+	w.Header().Add("Vary", y.fragmentHeader())
+
+	yc.setLogger(y.logger, requestIDOf(r))
+	yc.logger.Debug("[YagoTemplateServer] Handle HTTP Request", "method", method, "path", p)
 
 	y.Handle(yc)
 }
 
 func (y *YagoTemplateServer) Pattern() string {
-	return y.c.Route
+	return y.config().Route
 }
 
 func (y *YagoTemplateServer) Handler() http.Handler {
@@ -101,57 +255,118 @@ func (y *YagoTemplateServer) Handler() http.Handler {
 
 func (y *YagoTemplateServer) Handle(ctx *YagoContext) {
 
-	y.logger.Loglnf("[YagoTemplateServer] Handle request: %+v", ctx.query)
+	logger := ctx.Logger()
+	logger.Debug("[YagoTemplateServer] Handle request", "query", ctx.query)
 
 	hd, render, err := y.findHandler(ctx.serviceName)
 	if err != nil {
-		y.logger.Loglnf("[YagoTemplateServer] Handle HTTP Request fail for [%s] %s", ctx.serviceName, ctx.path)
-		ctx.w.WriteHeader(http.StatusNotFound)
+		logger.Debug("[YagoTemplateServer] Handle HTTP Request fail, handler not found", "path", ctx.path)
+		y.renderError(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	if err := y.findRenderError(ctx.serviceName); err != nil {
+		logger.Error("[YagoTemplateServer] Handle HTTP Request fail, template error", "path", ctx.path, "err", err)
+		y.renderError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
 	if hd == nil {
-		y.logger.Loglnf("[YagoTemplateServer] Handle HTTP Request fail for [%s] %s, empty handler", ctx.serviceName, ctx.path)
-		ctx.w.WriteHeader(http.StatusNotFound)
+		logger.Warn("[YagoTemplateServer] Handle HTTP Request fail, empty handler", "path", ctx.path)
+		y.renderError(ctx, http.StatusNotFound, errors.New("empty handler"))
 		return
 	}
 
 	switch ctx.r.Method {
 	case http.MethodGet:
-		renderData, err := hd(ctx)
+		renderData, err := y.traceInvoke(ctx, hd)
 		if err != nil {
-			y.logger.Log("[YagoTemplateServer] Handle HTTP GET Request fail for "+ctx.path, "logic handle fail")
+			if !reportPanicError(y.yago, ctx, err) {
+				logger.Warn("[YagoTemplateServer] Handle HTTP GET Request fail, logic handle fail", "path", ctx.path, "err", err)
+			}
+			y.renderError(ctx, StatusOfError(err), err)
+			return
+		}
+
+		renderSpan := ctx.Span().StartChild("render")
+		defer renderSpan.End()
+
+		if ctx.fragment != "" {
+			renderSpan.SetAttribute("fragment", ctx.fragment)
+			y.renderFragment(ctx, render, renderData)
 			return
 		}
 
 		if err := render.Render(ctx, renderData); err != nil {
-			y.logger.Log("[YagoTemplateServer] Handle HTTP Request fail for "+ctx.path, "render fail"+err.Error())
+			renderSpan.SetError(err)
+			if !reportPanicError(y.yago, ctx, err) {
+				logger.Error("[YagoTemplateServer] Handle HTTP Request fail, render fail", "path", ctx.path, "err", err)
+			}
+			if !render.stream {
+				y.renderError(ctx, http.StatusInternalServerError, err)
+			}
 			return
 		}
 		return
 	}
 
-	// case http.MethodPost:
-	// 	data, code := hd.Post(ctx)
-	// 	if code != 0 {
-	// 		y.logger.Loglnf("[YagoTemplateServer] Handle HTTP POST Request fail for %s, code: %d", ctx.path, code)
-	// 		ctx.writeResponseStatus(code)
-	// 		return
-	// 	}
-	// 	bs, _ := json.Marshal(data)
-	// 	ctx.w.Write(bs)
-	// 	return
-	// }
-	// y.logger.Log("[YagoTemplateServer] Handle HTTP Request fail for "+ctx.path, "handler not found")
-	ctx.writeResponseStatus(http.StatusNotFound)
+	y.renderError(ctx, http.StatusNotFound, errors.New("method not supported: "+ctx.r.Method))
+}
+
+// traceInvoke calls hd with the invoke span as the span of ctx, so spans the
+// handler starts are its children, a panic of it is returned as a *YagoPanicError
+func (y *YagoTemplateServer) traceInvoke(ctx *YagoContext, hd YaogoTemplateHandler) (data interface{}, err error) {
+	parent := ctx.span
+	ctx.span = parent.StartChild("invoke " + ctx.serviceName)
+	defer func() {
+		err = recoverPanic(recover(), err)
+		ctx.span.SetError(err)
+		ctx.span.End()
+		ctx.span = parent
+	}()
+
+	return hd(ctx)
+}
+
+func (y *YagoTemplateServer) renderFragment(ctx *YagoContext, render *YagoRender, data interface{}) {
+	err := render.RenderFragment(ctx, ctx.fragment, data)
+	if err == nil {
+		return
+	}
+	if !reportPanicError(y.yago, ctx, err) {
+		ctx.Logger().Warn("[YagoTemplateServer] Render fragment fail", "fragment", ctx.fragment, "path", ctx.path, "err", err)
+	}
+	if err == ErrFragmentNotFound {
+		y.renderError(ctx, http.StatusNotFound, err)
+		return
+	}
+	if !render.stream {
+		y.renderError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+func (y *YagoTemplateServer) fragmentHeader() string {
+	if y.config().FragmentHeader == "" {
+		return "X-Yago-Fragment"
+	}
+	return y.config().FragmentHeader
+}
+
+func (y *YagoTemplateServer) fragmentQuery() string {
+	if y.config().FragmentQuery == "" {
+		return "_fragment"
+	}
+	return y.config().FragmentQuery
 }
 
 func (y *YagoTemplateServer) findHandler(serviceName string) (h YaogoTemplateHandler, r *YagoRender, e error) {
 
+	y.mu.RLock()
 	hd, hOk := y.hds[serviceName]
 	render, rOk := y.renders[serviceName]
+	y.mu.RUnlock()
 
-	isNotFound := !hOk || !rOk
+	isNotFound := !hOk || (!rOk && !y.config().DevMode)
 
 	if isNotFound {
 		return nil, nil, errors.New("handler or render not found")
@@ -163,23 +378,77 @@ func (y *YagoTemplateServer) findHandler(serviceName string) (h YaogoTemplateHan
 	return
 }
 
-func (y *YagoTemplateServer) register(serviceName string, handler YaogoTemplateHandler, render *YagoRender) error {
+func (y *YagoTemplateServer) findRenderError(serviceName string) error {
+	y.mu.RLock()
+	defer y.mu.RUnlock()
+	return y.renderErrs[serviceName]
+}
+
+// ready reports whether the templates of every page are parsed, which happens when the
+// handler of the page is registered, so readiness waits for registration after startup.
+// Pages failing to parse, which DevMode registers to show the error, are not ready either
+func (y *YagoTemplateServer) ready() error {
+	y.mu.RLock()
+	defer y.mu.RUnlock()
+	for _, v := range y.config().PageLayouts {
+		if _, ok := y.hds[v.ServiceName]; !ok {
+			return fmt.Errorf("templates of %s on %s are not registered", v.ServiceName, y.config().Route)
+		}
+		if err := y.renderErrs[v.ServiceName]; err != nil {
+			return fmt.Errorf("templates of %s on %s fail to parse: %w", v.ServiceName, y.config().Route, err)
+		}
+	}
+	return nil
+}
+
+func (y *YagoTemplateServer) register(serviceName string, handler YaogoTemplateHandler, render *YagoRender, tmpls []string, renderErr error) error {
 	if y.hds == nil {
-		y.logger.Log("[YagoTemplateServer] Regist handler fail for " + serviceName)
+		y.logger.Error("[YagoTemplateServer] Regist handler fail", "service", serviceName)
 		return nil
 	}
 
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	if _, ok := y.hds[serviceName]; !ok {
+		if err := y.router.add(serviceName, y.pagePath(serviceName)); err != nil {
+			y.logger.Error("[YagoTemplateServer] Regist handler fail", "service", serviceName, "err", err)
+			return err
+		}
+	}
+
 	y.hds[serviceName] = handler
 	y.renders[serviceName] = render
-	y.logger.Log("[YagoTemplateServer] RegisterHandler succ for " + serviceName)
+	y.tmpls[serviceName] = tmpls
+	if renderErr != nil {
+		y.renderErrs[serviceName] = renderErr
+	} else {
+		delete(y.renderErrs, serviceName)
+	}
+	y.logger.Info("[YagoTemplateServer] RegisterHandler succ", "service", serviceName)
 	return nil
 }
 
+func (y *YagoTemplateServer) matchRoute(p string) (*yagoRoute, map[string]string) {
+	y.mu.RLock()
+	defer y.mu.RUnlock()
+	return y.router.match(p)
+}
+
+// pagePath returns the url pattern of a page, which is Path of its PageLayoutConfig
+// below Route, or /{Route}/{serviceName} when no Path configured
+func (y *YagoTemplateServer) pagePath(serviceName string) string {
+	if page := y.pageLayout(serviceName); page != nil && page.Path != "" {
+		return joinRoute(y.config().Route, page.Path)
+	}
+	return joinRoute(y.config().Route, serviceName)
+}
+
 func (y *YagoTemplateServer) parseQuery(query string) map[string]string {
 	r := make(map[string]string, 0)
 	values, err := url.ParseQuery(query)
 	if err != nil {
-		y.logger.Log("[YagoTemplateServer] ParseQuery fail for queryString: " + query)
+		y.logger.Debug("[YagoTemplateServer] ParseQuery fail", "query", query)
 		return r
 	}
 	for k, v := range values {
@@ -195,45 +464,70 @@ func (y *YagoTemplateServer) Type() string {
 // Register
 func (y *YagoTemplateServer) Register(service string, handler YaogoTemplateHandler) error {
 
-	tmpls := y.getBindTemplates(service)
-
-	render, err := NewRenderWithTemplates(tmpls, y.bindFuncs)
+	tmpls, err := y.getBindTemplates(service)
 	if err != nil {
-		y.logger.Log("[YagoServer] RegisterRouter fail with binding templates:", tmpls, "err is "+err.Error())
+		y.logger.Error("[YagoServer] RegisterRouter fail", "service", service, "err", err)
 		return err
 	}
 
-	y.logger.Log("[YagoServer] RegisterRouter succ with binding templates:", tmpls)
+	render, err := y.newRender(service, tmpls)
+	if err != nil {
+		y.logger.Error("[YagoServer] RegisterRouter fail with binding templates", "service", service, "templates", tmpls, "err", err)
+		if !y.config().DevMode {
+			return err
+		}
+		return y.register(service, handler, nil, tmpls, err)
+	}
+
+	y.logger.Info("[YagoServer] RegisterRouter succ with binding templates", "service", service, "templates", tmpls)
 
-	return y.register(service, handler, render)
+	return y.register(service, handler, render, tmpls, nil)
 }
 
-func (y *YagoTemplateServer) getBindTemplates(serviceName string) []string {
-
-	t := []string{}
-	b := []string{}
-	if y.c.BaseLayouts != nil && len(y.c.BaseLayouts) > 0 {
-		b = append(b, y.c.BaseLayouts...)
+func (y *YagoTemplateServer) newRender(serviceName string, tmpls []string) (*YagoRender, error) {
+	render, err := NewRenderWithFS(y.layouts(), tmpls, y.bindFuncs)
+	if err != nil {
+		return nil, err
 	}
-	if y.c.PageLayouts == nil {
-		return y.getBindTemplatesWithDistDir(append(t, b...))
+	page := y.pageLayout(serviceName)
+	if page == nil {
+		return render, nil
+	}
+	if page.Layout != "" {
+		if err := y.checkLayoutBlocks(page, render); err != nil {
+			return nil, err
+		}
 	}
-	for _, v := range y.c.PageLayouts {
+	render.SetStream(page.Stream)
+	return render, nil
+}
+
+func (y *YagoTemplateServer) pageLayout(serviceName string) *PageLayoutConfig {
+	for _, v := range y.config().PageLayouts {
 		if v.ServiceName == serviceName {
-			t = append(t, v.Templates...)
-			break
+			return v
 		}
 	}
-	return y.getBindTemplatesWithDistDir(append(t, b...))
+	return nil
 }
 
-func (y *YagoTemplateServer) getBindTemplatesWithDistDir(t []string) []string {
-	if y.c.LayoutDir == "" {
-		return t
+// getBindTemplates returns the templates of a page, the first one is the entry of the render.
+// Pages extending a layout are bound as: root layout, BaseLayouts, nested layouts, page.
+// Other pages are bound as: page, BaseLayouts
+func (y *YagoTemplateServer) getBindTemplates(serviceName string) ([]string, error) {
+
+	page := y.pageLayout(serviceName)
+	if page != nil && page.Layout != "" {
+		t, err := y.getLayoutTemplates(page.Layout)
+		if err != nil {
+			return nil, err
+		}
+		return append(t, page.Templates...), nil
 	}
-	r := []string{}
-	for _, v := range t {
-		r = append(r, y.c.LayoutDir+"/"+v)
+
+	t := []string{}
+	if page != nil {
+		t = append(t, page.Templates...)
 	}
-	return r
+	return append(t, y.config().BaseLayouts...), nil
 }
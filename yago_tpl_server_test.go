@@ -0,0 +1,248 @@
+package yago
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTemplateServer(t *testing.T, c *YagoTemplateConfig, fsys fstest.MapFS) *YagoTemplateServer {
+	c.FS = fsys
+	ts, err := NewYagoTemplateServer(c)
+	assert.Nil(t, err)
+	return ts
+}
+
+func TestYagoTemplateServerErrorPage(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"page.layout":  {Data: []byte(`{{template "base" .}}`)},
+		"base.layout":  {Data: []byte(`{{define "base"}}[{{block "content" .}}{{end}}]{{end}}`)},
+		"error.layout": {Data: []byte(`{{template "base" .}}{{define "content"}}{{.Status}}:{{.Error}}{{end}}`)},
+		"404.layout":   {Data: []byte(`{{template "base" .}}{{define "content"}}missing {{.Path}}{{end}}`)},
+	}
+
+	ts := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/p",
+		BaseLayouts: []string{"base.layout"},
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "page", Templates: []string{"page.layout"}}},
+		ErrorLayouts: []*ErrorLayoutConfig{
+			{Status: 0, Templates: []string{"error.layout"}},
+			{Status: http.StatusNotFound, Templates: []string{"404.layout"}},
+		},
+	}, fsys)
+	assert.Nil(t, ts.Register("page", func(ctx *YagoContext) (interface{}, error) {
+		if ctx.Query("deny") != "" {
+			return nil, NewYagoHTTPError(http.StatusForbidden, errors.New("denied"))
+		}
+		return nil, errors.New("boom")
+	}))
+
+	var uts = []struct {
+		Path   string
+		Status int
+		Body   string
+	}{
+		{Path: "/p/page", Status: http.StatusInternalServerError, Body: "[500:boom]"},
+		{Path: "/p/page?deny=1", Status: http.StatusForbidden, Body: "[403:denied]"},
+		{Path: "/p/none", Status: http.StatusNotFound, Body: "[missing /p/none]"},
+	}
+	for _, uc := range uts {
+		w := httptest.NewRecorder()
+		ts.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uc.Path, nil))
+		assert.Equal(t, uc.Status, w.Code, uc.Path)
+		assert.Equal(t, uc.Body, w.Body.String(), uc.Path)
+	}
+}
+
+type testPageData struct {
+	Name string
+	Fail bool
+}
+
+func (p *testPageData) Value() (string, error) {
+	if p.Fail {
+		return "", errors.New("fail")
+	}
+	return "ok", nil
+}
+
+func TestYagoTemplateServerBufferedRender(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"page.layout": {Data: []byte(`head {{.Name}} {{.Value}} tail`)},
+	}
+
+	ts := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/p",
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "page", Templates: []string{"page.layout"}}},
+	}, fsys)
+	assert.Nil(t, ts.Register("page", func(ctx *YagoContext) (interface{}, error) {
+		return &testPageData{Name: "yago", Fail: ctx.Query("fail") != ""}, nil
+	}))
+
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/p/page", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "head yago ok tail", w.Body.String())
+	assert.Equal(t, "17", w.Header().Get("Content-Length"))
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	ts.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/p/page?fail=1", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "head")
+}
+
+func TestYagoTemplateServerFragment(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"page.layout": {Data: []byte(`<html>{{block "list" .}}<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}</html>`)},
+	}
+
+	ts := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/p",
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "page", Templates: []string{"page.layout"}}},
+	}, fsys)
+	assert.Nil(t, ts.Register("page", func(ctx *YagoContext) (interface{}, error) {
+		return []string{"a", "b"}, nil
+	}))
+
+	var uts = []struct {
+		Path   string
+		Header string
+		Status int
+		Body   string
+	}{
+		{Path: "/p/page", Status: http.StatusOK, Body: "<html><ul><li>a</li><li>b</li></ul></html>"},
+		{Path: "/p/page", Header: "list", Status: http.StatusOK, Body: "<ul><li>a</li><li>b</li></ul>"},
+		{Path: "/p/page?_fragment=list", Status: http.StatusOK, Body: "<ul><li>a</li><li>b</li></ul>"},
+		{Path: "/p/page?_fragment=none", Status: http.StatusNotFound},
+	}
+	for _, uc := range uts {
+		r := httptest.NewRequest(http.MethodGet, uc.Path, nil)
+		if uc.Header != "" {
+			r.Header.Set("X-Yago-Fragment", uc.Header)
+		}
+		w := httptest.NewRecorder()
+		ts.ServeHTTP(w, r)
+		assert.Equal(t, uc.Status, w.Code, uc.Path)
+		assert.Equal(t, "X-Yago-Fragment", w.Header().Get("Vary"))
+		if uc.Body != "" {
+			assert.Equal(t, uc.Body, w.Body.String(), uc.Path)
+		}
+	}
+}
+
+func TestYagoTemplateServerRouting(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"page.layout": {Data: []byte(`{{.}}`)},
+	}
+
+	ts := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route: "/",
+		PageLayouts: []*PageLayoutConfig{
+			{ServiceName: "index", Path: "/", Templates: []string{"page.layout"}},
+			{ServiceName: "archive", Path: "/app/todo/archive", Templates: []string{"page.layout"}},
+			{ServiceName: "item", Path: "/app/todo/{id}", Templates: []string{"page.layout"}},
+			{ServiceName: "setting", Templates: []string{"page.layout"}},
+		},
+	}, fsys)
+	for _, name := range []string{"index", "archive", "item", "setting"} {
+		assert.Nil(t, ts.Register(name, func(ctx *YagoContext) (interface{}, error) {
+			return ctx.ServiceName() + ctx.Param("id"), nil
+		}))
+	}
+
+	var uts = []struct {
+		Path   string
+		Status int
+		Body   string
+	}{
+		{Path: "/", Status: http.StatusOK, Body: "index"},
+		{Path: "/app/todo/archive", Status: http.StatusOK, Body: "archive"},
+		{Path: "/app/todo/7", Status: http.StatusOK, Body: "item7"},
+		{Path: "/setting", Status: http.StatusOK, Body: "setting"},
+		{Path: "/app", Status: http.StatusNotFound},
+	}
+	for _, uc := range uts {
+		w := httptest.NewRecorder()
+		ts.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uc.Path, nil))
+		assert.Equal(t, uc.Status, w.Code, uc.Path)
+		if uc.Body != "" {
+			assert.Equal(t, uc.Body, w.Body.String(), uc.Path)
+		}
+	}
+}
+
+func TestYagoTemplateServerLayouts(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"base.layout":    {Data: []byte(`<title>{{block "title" .}}yago{{end}}</title>{{template "nav" .}}{{block "content" .}}{{end}}`)},
+		"nav.layout":     {Data: []byte(`{{define "nav"}}<nav/>{{end}}`)},
+		"app.layout":     {Data: []byte(`{{define "content"}}<aside>{{block "sidebar" .}}{{end}}</aside>{{block "main" .}}{{end}}{{end}}`)},
+		"home.layout":    {Data: []byte(`{{define "content"}}home{{end}}`)},
+		"todo.layout":    {Data: []byte(`{{define "title"}}todo{{end}}{{define "sidebar"}}side{{end}}{{define "main"}}{{template "item" .}}{{end}}{{define "item"}}item{{end}}`)},
+		"typo.layout":    {Data: []byte(`{{define "sidbar"}}side{{end}}`)},
+		"missing.layout": {Data: []byte(`{{define "main"}}{{template "footer" .}}{{end}}`)},
+	}
+
+	ts := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/",
+		BaseLayouts: []string{"nav.layout"},
+		Layouts: []*LayoutConfig{
+			{Name: "base", Templates: []string{"base.layout"}},
+			{Name: "app", Extends: "base", Templates: []string{"app.layout"}},
+			{Name: "loop", Extends: "loop"},
+			{Name: "orphan", Extends: "none"},
+		},
+		PageLayouts: []*PageLayoutConfig{
+			{ServiceName: "home", Layout: "base", Templates: []string{"home.layout"}},
+			{ServiceName: "todo", Layout: "app", Templates: []string{"todo.layout"}},
+			{ServiceName: "typo", Layout: "app", Templates: []string{"typo.layout"}},
+			{ServiceName: "missing", Layout: "app", Templates: []string{"missing.layout"}},
+			{ServiceName: "loop", Layout: "loop"},
+			{ServiceName: "orphan", Layout: "orphan"},
+		},
+	}, fsys)
+
+	hd := func(ctx *YagoContext) (interface{}, error) { return nil, nil }
+	assert.Nil(t, ts.Register("home", hd))
+	assert.Nil(t, ts.Register("todo", hd))
+	assert.EqualError(t, ts.Register("typo", hd), `page "typo" defines block "sidbar" which layout "app" does not declare`)
+	assert.EqualError(t, ts.Register("missing", hd), `page "missing" references undefined block "footer"`)
+	assert.EqualError(t, ts.Register("loop", hd), `layout "loop" extends itself`)
+	assert.EqualError(t, ts.Register("orphan", hd), `layout "orphan" extends undefined layout "none"`)
+
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/home", nil))
+	assert.Equal(t, "<title>yago</title><nav/>home", w.Body.String())
+
+	w = httptest.NewRecorder()
+	ts.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todo", nil))
+	assert.Equal(t, "<title>todo</title><nav/><aside>side</aside>item", w.Body.String())
+}
+
+func TestYagoTemplateServerFuncs(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"page.layout": {Data: []byte(`{{url "item" "id" 7 "tab" "a b"}} {{asset "css/base.css"}} {{hello}}`)},
+	}
+
+	ts := newTestTemplateServer(t, &YagoTemplateConfig{
+		Route:       "/app",
+		AssetPrefix: "/static",
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "item", Path: "/todo/{id}", Templates: []string{"page.layout"}}},
+	}, fsys)
+	ts.BindFuncs(map[string]interface{}{"hello": func() string { return "hi" }})
+	assert.Nil(t, ts.Register("item", func(ctx *YagoContext) (interface{}, error) { return nil, nil }))
+
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app/todo/7", nil))
+	assert.Equal(t, "/app/todo/7?tab=a&#43;b /static/css/base.css hi", w.Body.String())
+}
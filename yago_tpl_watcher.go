@@ -0,0 +1,126 @@
+package yago
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"time"
+)
+
+const defaultTemplateReloadInterval = 1000
+
+type yagoFileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch polls the layout files when DevMode is enabled and re-parses every render
+// bound to a changed template file, it blocks until ctx is done
+func (y *YagoTemplateServer) Watch(ctx context.Context) {
+
+	if !y.config().DevMode {
+		return
+	}
+
+	interval := y.config().ReloadInterval
+	if interval <= 0 {
+		interval = defaultTemplateReloadInterval
+	}
+
+	ticker := time.NewTicker(time.Millisecond * time.Duration(interval))
+	defer ticker.Stop()
+
+	y.logger.Info("[YagoTemplateServer] DevMode watching layouts", "dir", y.config().LayoutDir, "intervalMs", interval)
+
+	stamps := y.scanLayouts()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := y.scanLayouts()
+		changed := diffFileStamps(stamps, current)
+		stamps = current
+		if len(changed) == 0 {
+			continue
+		}
+		y.reload(changed)
+	}
+}
+
+func (y *YagoTemplateServer) scanLayouts() map[string]yagoFileStamp {
+
+	stamps := make(map[string]yagoFileStamp)
+
+	fs.WalkDir(y.layouts(), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		stamps[y.layoutKey(p)] = yagoFileStamp{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return stamps
+}
+
+// layoutKey normalizes a template path so the scanned files and the bound
+// templates of renders can be compared with each other
+func (y *YagoTemplateServer) layoutKey(p string) string {
+	return path.Clean(p)
+}
+
+func diffFileStamps(old, current map[string]yagoFileStamp) map[string]bool {
+	changed := make(map[string]bool)
+	for p, s := range current {
+		if o, ok := old[p]; !ok || o != s {
+			changed[p] = true
+		}
+	}
+	for p := range old {
+		if _, ok := current[p]; !ok {
+			changed[p] = true
+		}
+	}
+	return changed
+}
+
+// reload re-parses all renders depending on changed files, each render is
+// parsed aside and swapped in as a whole so requests never see a half state
+func (y *YagoTemplateServer) reload(changed map[string]bool) {
+
+	y.mu.RLock()
+	affected := make(map[string][]string)
+	for service, tmpls := range y.tmpls {
+		for _, t := range tmpls {
+			if changed[y.layoutKey(t)] {
+				affected[service] = tmpls
+				break
+			}
+		}
+	}
+	y.mu.RUnlock()
+
+	for service, tmpls := range affected {
+		render, err := y.newRender(service, tmpls)
+
+		y.mu.Lock()
+		if err != nil {
+			y.renderErrs[service] = err
+		} else {
+			y.renders[service] = render
+			delete(y.renderErrs, service)
+		}
+		y.mu.Unlock()
+
+		if err != nil {
+			y.logger.Error("[YagoTemplateServer] Reload templates fail", "service", service, "err", err)
+			continue
+		}
+		y.logger.Info("[YagoTemplateServer] Reload templates succ", "service", service)
+	}
+}
@@ -0,0 +1,34 @@
+package yago
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYagoTemplateServerReload(t *testing.T) {
+
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.layout")
+	assert.Nil(t, os.WriteFile(page, []byte(`{{define "page"}}v1{{end}}{{template "page"}}`), 0644))
+
+	ts, _ := NewYagoTemplateServer(&YagoTemplateConfig{
+		Route:       "/p",
+		LayoutDir:   dir,
+		DevMode:     true,
+		PageLayouts: []*PageLayoutConfig{{ServiceName: "page", Templates: []string{"page.layout"}}},
+	})
+	assert.Nil(t, ts.Register("page", func(ctx *YagoContext) (interface{}, error) { return nil, nil }))
+
+	stamps := ts.scanLayouts()
+	assert.Nil(t, os.WriteFile(page, []byte(`{{define "page"}}{{end`), 0644))
+	ts.reload(diffFileStamps(stamps, ts.scanLayouts()))
+	assert.NotNil(t, ts.findRenderError("page"))
+
+	stamps = ts.scanLayouts()
+	assert.Nil(t, os.WriteFile(page, []byte(`{{define "page"}}v2!{{end}}{{template "page"}}`), 0644))
+	ts.reload(diffFileStamps(stamps, ts.scanLayouts()))
+	assert.Nil(t, ts.findRenderError("page"))
+}
@@ -3,7 +3,7 @@ package yago
 import "net/http"
 
 type YagoWsServerConfig struct {
-	Route string
+	Route string `json:"route"`
 }
 
 type YagoWsServer struct {